@@ -12,12 +12,15 @@ import (
 
 	"menu-parser/internal/repository"
 	httpDelivery "menu-parser/internal/transport/http"
+	"menu-parser/internal/transport/queue"
 	"menu-parser/internal/usecase"
+	"menu-parser/pkg/auth"
 	"menu-parser/pkg/config"
 	"menu-parser/pkg/database"
 	"menu-parser/pkg/health"
 	"menu-parser/pkg/parser"
 	rabbitmqQueue "menu-parser/pkg/queue"
+	"menu-parser/pkg/webhook"
 )
 
 func main() {
@@ -32,30 +35,62 @@ func main() {
 	}
 	defer db.Close(context.Background())
 
-	rabbitmq, err := rabbitmqQueue.NewRabbitMQ(cfg)
+	queueBackend, err := rabbitmqQueue.NewBackend(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize queue: %v", err)
 	}
-	defer rabbitmq.Close()
+	defer queueBackend.Close()
 
 	menuRepo := repository.NewMenuRepository(db)
 	taskRepo := repository.NewTaskRepository(db)
 	auditRepo := repository.NewAuditRepository(db)
+	mappingRepo := repository.NewColumnMappingRepository(db)
+	restaurantRepo := repository.NewRestaurantRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	dlqAuditRepo := repository.NewDeadLetterAuditRepository(db)
+	transactor := repository.NewTransactor(db)
 
 	sheetsParser, err := parser.NewSheetsParser(cfg.GoogleSheetsCredentialsPath)
 	if err != nil {
 		log.Fatalf("Failed to initialize parser: %v", err)
 	}
 
-	queuePublisher := rabbitmqQueue.NewQueuePublisher(rabbitmq)
+	healthService := health.NewHealthService(db, queueBackend)
 
-	healthService := health.NewHealthService(db, rabbitmq)
+	authenticator, err := auth.NewAuthenticator(cfg.AuthAPIKeys, cfg.AuthJWTSecret)
+	if err != nil {
+		log.Fatalf("Failed to initialize authentication: %v", err)
+	}
 
-	menuUseCase := usecase.NewMenuUseCase(menuRepo, taskRepo, sheetsParser, queuePublisher)
-	productUseCase := usecase.NewProductUseCase(menuRepo, auditRepo, queuePublisher)
+	menuUseCase := usecase.NewMenuUseCase(menuRepo, taskRepo, mappingRepo, auditRepo, restaurantRepo, outboxRepo, transactor, sheetsParser, cfg.WebhookSecret != "")
+	productUseCase := usecase.NewProductUseCase(menuRepo, auditRepo, outboxRepo)
+	mappingUseCase := usecase.NewColumnMappingUseCase(mappingRepo)
+	restaurantUseCase := usecase.NewRestaurantUseCase(restaurantRepo)
+	auditUseCase := usecase.NewAuditUseCase(auditRepo, menuRepo)
+	dlqUseCase := usecase.NewDLQUseCase(queueBackend.DeadLetters, taskRepo, dlqAuditRepo)
 	healthUseCase := usecase.NewHealthUseCase(healthService)
+	webhookSender := webhook.NewSender(cfg.WebhookSecret, cfg.WebhookTimeout, cfg.WebhookAllowPrivateNetworks)
+	outboxRelay := usecase.NewOutboxRelay(outboxRepo, taskRepo, queueBackend.Publisher, webhookSender, cfg.OutboxPollInterval, cfg.OutboxMaxAttempts)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go outboxRelay.Run(backgroundCtx)
+
+	// With the in-memory queue nothing leaves this process, so the API does the worker's job too
+	var consumer *queue.Consumer
+	if queueBackend.InProcess {
+		queueConsumer, err := queueBackend.NewConsumer()
+		if err != nil {
+			log.Fatalf("Failed to initialize queue consumer: %v", err)
+		}
+		taskReaper := usecase.NewTaskReaper(taskRepo, outboxRepo, transactor, cfg.TaskStaleAfter, cfg.TaskReaperInterval, queue.MaxRetries)
+		go taskReaper.Run(backgroundCtx)
 
-	router := httpDelivery.SetupRouter(menuUseCase, productUseCase, healthUseCase)
+		consumer = queue.NewConsumer(menuUseCase, productUseCase, taskRepo, queueConsumer, cfg.MenuParsingConcurrency, cfg.ProductStatusConcurrency)
+		consumer.Run()
+	}
+
+	router := httpDelivery.SetupRouter(menuUseCase, productUseCase, mappingUseCase, restaurantUseCase, auditUseCase, dlqUseCase, healthUseCase, authenticator)
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf("%s:%s", cfg.APIHost, cfg.APIPort),
@@ -74,6 +109,10 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopBackground()
+	if consumer != nil {
+		consumer.Shutdown()
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
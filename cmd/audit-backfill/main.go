@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"menu-parser/internal/repository"
+	"menu-parser/internal/usecase"
+	"menu-parser/pkg/config"
+	"menu-parser/pkg/database"
+)
+
+// audit-backfill is a one-off command that fills in restaurant_id, menu_id and
+// product_name of product_status_audit records written before they were recorded
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would be updated without writing")
+	batchSize := flag.Int("batch-size", 500, "number of audit records read per batch")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := database.NewMongoDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close(context.Background())
+
+	menuRepo := repository.NewMenuRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+
+	auditUseCase := usecase.NewAuditUseCase(auditRepo, menuRepo)
+
+	result, err := auditUseCase.BackfillContext(context.Background(), *batchSize, *dryRun)
+	if result != nil {
+		log.Printf("Scanned %d audit records: %d updated, %d ambiguous, %d without a matching menu (dry run: %t)",
+			result.Scanned, result.Updated, result.Ambiguous, result.Unmatched, *dryRun)
+	}
+	if err != nil {
+		log.Fatalf("Backfill failed: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"menu-parser/internal/repository"
+	"menu-parser/internal/usecase"
+	"menu-parser/pkg/database"
+	rabbitmqQueue "menu-parser/pkg/queue"
+)
+
+const dlqUsage = "usage: worker dlq list|replay|purge [-limit N] [-all] [message-id...]"
+
+// runDLQCommand handles `worker dlq ...`: it lists, replays or purges
+// dead-lettered messages and prints them as JSON
+func runDLQCommand(args []string, db *database.MongoDB, queueBackend *rabbitmqQueue.Backend) {
+	if len(args) == 0 {
+		log.Fatal(dlqUsage)
+	}
+	// The in-memory DLQ lives in the API process that runs the pipeline; this
+	// process would only ever see its own empty queue
+	if queueBackend.InProcess {
+		log.Fatalf("dlq commands need QUEUE_BACKEND=%s; with QUEUE_BACKEND=%s use /api/v1/admin/dlq of the API",
+			rabbitmqQueue.BackendRabbitMQ, rabbitmqQueue.BackendMemory)
+	}
+
+	flags := flag.NewFlagSet("dlq "+args[0], flag.ExitOnError)
+	limit := flags.Int("limit", 0, "number of messages scanned from the head of the DLQ (default 100)")
+	all := flags.Bool("all", false, "select every scanned message")
+	flags.Parse(args[1:])
+
+	userID := "cli"
+	if user := os.Getenv("USER"); user != "" {
+		userID = "cli:" + user
+	}
+
+	dlqUseCase := usecase.NewDLQUseCase(
+		queueBackend.DeadLetters,
+		repository.NewTaskRepository(db),
+		repository.NewDeadLetterAuditRepository(db),
+	)
+
+	ctx := context.Background()
+	var err error
+	var letters interface{}
+	switch args[0] {
+	case "list":
+		letters, err = dlqUseCase.List(ctx, *limit)
+	case "replay":
+		letters, err = dlqUseCase.Replay(ctx, flags.Args(), *all, *limit, userID)
+	case "purge":
+		letters, err = dlqUseCase.Purge(ctx, flags.Args(), *all, *limit, userID)
+	default:
+		log.Fatal(dlqUsage)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if encErr := encoder.Encode(letters); encErr != nil {
+		log.Printf("Failed to print dead letters: %v", encErr)
+	}
+	if err != nil {
+		log.Fatalf("dlq %s failed: %v", args[0], err)
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"log"
+	"net/http"
+	"os"
 
 	"menu-parser/internal/repository"
 	"menu-parser/internal/transport/queue"
@@ -11,6 +14,7 @@ import (
 	"menu-parser/pkg/database"
 	"menu-parser/pkg/parser"
 	rabbitmqQueue "menu-parser/pkg/queue"
+	"menu-parser/pkg/webhook"
 )
 
 func main() {
@@ -25,31 +29,62 @@ func main() {
 	}
 	defer db.Close(context.Background())
 
-	rabbitmqInstance, err := rabbitmqQueue.NewRabbitMQ(cfg)
+	queueBackend, err := rabbitmqQueue.NewBackend(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize queue: %v", err)
 	}
-	defer rabbitmqInstance.Close()
+	defer queueBackend.Close()
+
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		runDLQCommand(os.Args[2:], db, queueBackend)
+		return
+	}
+
+	if queueBackend.InProcess {
+		log.Printf("Queue backend %q only delivers messages published by this process", cfg.QueueBackend)
+	}
 
 	menuRepo := repository.NewMenuRepository(db)
 	taskRepo := repository.NewTaskRepository(db)
 	auditRepo := repository.NewAuditRepository(db)
+	mappingRepo := repository.NewColumnMappingRepository(db)
+	restaurantRepo := repository.NewRestaurantRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	transactor := repository.NewTransactor(db)
 
 	sheetsParser, err := parser.NewSheetsParser(cfg.GoogleSheetsCredentialsPath)
 	if err != nil {
 		log.Fatalf("Failed to initialize parser: %v", err)
 	}
 
-	queuePublisher := rabbitmqQueue.NewQueuePublisher(rabbitmqInstance)
-	queueConsumer, err := rabbitmqQueue.NewQueueConsumer(rabbitmqInstance)
+	queueConsumer, err := queueBackend.NewConsumer()
 	if err != nil {
 		log.Fatalf("Failed to initialize queue consumer: %v", err)
 	}
 
-	menuUseCase := usecase.NewMenuUseCase(menuRepo, taskRepo, sheetsParser, queuePublisher)
-	productUseCase := usecase.NewProductUseCase(menuRepo, auditRepo, queuePublisher)
+	menuUseCase := usecase.NewMenuUseCase(menuRepo, taskRepo, mappingRepo, auditRepo, restaurantRepo, outboxRepo, transactor, sheetsParser, cfg.WebhookSecret != "")
+	productUseCase := usecase.NewProductUseCase(menuRepo, auditRepo, outboxRepo)
+	webhookSender := webhook.NewSender(cfg.WebhookSecret, cfg.WebhookTimeout, cfg.WebhookAllowPrivateNetworks)
+	outboxRelay := usecase.NewOutboxRelay(outboxRepo, taskRepo, queueBackend.Publisher, webhookSender, cfg.OutboxPollInterval, cfg.OutboxMaxAttempts)
+	taskReaper := usecase.NewTaskReaper(taskRepo, outboxRepo, transactor, cfg.TaskStaleAfter, cfg.TaskReaperInterval, queue.MaxRetries)
+
+	consumer := queue.NewConsumer(menuUseCase, productUseCase, taskRepo, queueConsumer, cfg.MenuParsingConcurrency, cfg.ProductStatusConcurrency)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// The worker relays too, so messages it writes to the outbox do not depend on the API running
+	go outboxRelay.Run(backgroundCtx)
+	go taskReaper.Run(backgroundCtx)
 
-	consumer := queue.NewConsumer(menuUseCase, productUseCase, taskRepo, queueConsumer)
+	// Counters published with expvar, e.g. task_reaper_requeued_total
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/debug/vars", expvar.Handler())
+	go func() {
+		if err := http.ListenAndServe(":"+cfg.WorkerMetricsPort, metricsMux); err != nil {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
 
 	consumer.Start()
 }
@@ -0,0 +1,92 @@
+// Package apperror defines typed errors shared by the domain, the repositories
+// and the parser, so callers can tell a bad request from a missing resource or
+// an outage without matching on error strings.
+package apperror
+
+import "errors"
+
+// Kind is the category of an error
+type Kind string
+
+const (
+	KindInvalidArgument  Kind = "invalid_argument"
+	KindUnauthenticated  Kind = "unauthenticated"
+	KindNotFound         Kind = "not_found"
+	KindPermissionDenied Kind = "permission_denied"
+	KindConflict         Kind = "conflict"
+	// KindUnavailable marks transient failures of a dependency: timeouts, rate limits, outages
+	KindUnavailable Kind = "unavailable"
+	KindInternal    Kind = "internal"
+)
+
+// CodeInternal is reported for errors that carry no code
+const CodeInternal = "internal"
+
+// Error is an error with a kind and a stable machine-readable code
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Err     error
+}
+
+// New creates an error without a cause
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Wrap creates an error caused by err
+func Wrap(err error, kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf returns the kind of the first *Error in err's chain, KindInternal if there is none
+func KindOf(err error) Kind {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Kind
+	}
+	return KindInternal
+}
+
+// CodeOf returns the code of the first *Error in err's chain, CodeInternal if there is none
+func CodeOf(err error) string {
+	var appErr *Error
+	if errors.As(err, &appErr) && appErr.Code != "" {
+		return appErr.Code
+	}
+	return CodeInternal
+}
+
+// MessageOf returns the message of the first *Error in err's chain without the
+// causes it wraps, which may hold driver, broker or HTTP client details. Errors
+// of KindInternal are described only as "internal error".
+func MessageOf(err error) string {
+	var appErr *Error
+	if !errors.As(err, &appErr) || appErr.Kind == KindInternal {
+		return "internal error"
+	}
+	return appErr.Message
+}
+
+// IsPermanent reports whether retrying the failed operation cannot succeed.
+// Unavailable and unclassified errors are treated as transient.
+func IsPermanent(err error) bool {
+	switch KindOf(err) {
+	case KindInvalidArgument, KindUnauthenticated, KindNotFound, KindPermissionDenied, KindConflict:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,28 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMessageOf(t *testing.T) {
+	cause := errors.New("connection refused: 10.0.0.5:27017")
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"typed", New(KindNotFound, "sheet_not_found", `sheet "Бар" not found`), `sheet "Бар" not found`},
+		{"wrapped cause is dropped", Wrap(cause, KindUnavailable, "database_unavailable", "database unavailable"), "database unavailable"},
+		{"wrapped by fmt", fmt.Errorf("failed to parse menu: %w", New(KindInvalidArgument, "empty_spreadsheet", "no data found")), "no data found"},
+		{"internal kind", Wrap(cause, KindInternal, "sheets_error", "unexpected Sheets API error"), "internal error"},
+		{"untyped", cause, "internal error"},
+	}
+
+	for _, tt := range tests {
+		if got := MessageOf(tt.err); got != tt.want {
+			t.Errorf("%s: MessageOf = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
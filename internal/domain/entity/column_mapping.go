@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"menu-parser/internal/domain/apperror"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultColumnMappingProfile is the profile name looked up for a restaurant
+// when a parse request does not name one explicitly
+const DefaultColumnMappingProfile = "default"
+
+// ColumnMapping describes where menu fields live in a sheet. Every column
+// reference is either a header name (matched case-insensitively against
+// HeaderRow) or a column letter such as "B".
+type ColumnMapping struct {
+	HeaderRow   int    `json:"header_row,omitempty" bson:"header_row,omitempty"`
+	ExtID       string `json:"ext_id,omitempty" bson:"ext_id,omitempty"`
+	ProductName string `json:"product_name" bson:"product_name"`
+	Price       string `json:"price,omitempty" bson:"price,omitempty"`
+	PriceOld    string `json:"price_old,omitempty" bson:"price_old,omitempty"`
+	Options     string `json:"options,omitempty" bson:"options,omitempty"`
+}
+
+// DefaultColumnMapping returns the layout the parser historically assumed
+func DefaultColumnMapping() *ColumnMapping {
+	return &ColumnMapping{
+		ProductName: "B",
+		Price:       "D",
+		PriceOld:    "E",
+		Options:     "H",
+	}
+}
+
+// Validate checks that the mapping can be applied to a sheet
+func (m *ColumnMapping) Validate() error {
+	if strings.TrimSpace(m.ProductName) == "" {
+		return apperror.New(apperror.KindInvalidArgument, "invalid_column_mapping", "column mapping: product_name column is required")
+	}
+	if m.HeaderRow < 0 {
+		return apperror.New(apperror.KindInvalidArgument, "invalid_column_mapping", "column mapping: header_row must not be negative")
+	}
+	return nil
+}
+
+// ColumnMappingProfile is a named column mapping stored for a restaurant
+type ColumnMappingProfile struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	RestaurantID string             `json:"restaurant_id" bson:"restaurant_id"`
+	Name         string             `json:"name" bson:"name"`
+	Mapping      ColumnMapping      `json:"mapping" bson:"mapping"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+}
@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeadLetter is a message in the dead-letter queue, described by the x-death
+// header the broker added when it dead-lettered the message
+type DeadLetter struct {
+	MessageID   string `json:"message_id"`
+	SourceQueue string `json:"source_queue"`
+	// Reason is the x-death reason: rejected, expired, maxlen or delivery_limit
+	Reason     string          `json:"reason"`
+	DeathCount int64           `json:"death_count"`
+	DiedAt     *time.Time      `json:"died_at,omitempty"`
+	TaskID     string          `json:"task_id,omitempty"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type DeadLetterAction string
+
+const (
+	DeadLetterActionReplay DeadLetterAction = "replay"
+	DeadLetterActionPurge  DeadLetterAction = "purge"
+)
+
+// DeadLetterAudit records a replay or purge of a dead-lettered message
+type DeadLetterAudit struct {
+	ID          primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Action      DeadLetterAction   `json:"action" bson:"action"`
+	MessageID   string             `json:"message_id" bson:"message_id"`
+	SourceQueue string             `json:"source_queue" bson:"source_queue"`
+	Reason      string             `json:"reason" bson:"reason"`
+	TaskID      string             `json:"task_id,omitempty" bson:"task_id,omitempty"`
+	Body        string             `json:"body" bson:"body"`
+	UserID      string             `json:"user_id" bson:"user_id"`
+	Timestamp   time.Time          `json:"timestamp" bson:"timestamp"`
+}
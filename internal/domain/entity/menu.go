@@ -10,20 +10,41 @@ type Menu struct {
 	ID               primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
 	Name             string             `json:"name" bson:"name"`
 	RestaurantID     string             `json:"restaurant_id" bson:"restaurant_id"`
+	Version          int                `json:"version" bson:"version,omitempty"`
 	Products         []Product          `json:"products" bson:"products"`
 	AttributesGroups []AttributesGroup  `json:"attributes_groups" bson:"attributes_groups"`
 	Attributes       []Attribute        `json:"attributes" bson:"attributes"`
 	CreatedAt        time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt        time.Time          `json:"updated_at" bson:"updated_at"`
+	// Revision counts writes to the menu document; a merge only applies if
+	// the revision it read is still current
+	Revision int64 `json:"-" bson:"revision,omitempty"`
+}
+
+// MenuVersion summarises one stored version of a restaurant's menu
+type MenuVersion struct {
+	MenuID        primitive.ObjectID `json:"menu_id" bson:"_id"`
+	Version       int                `json:"version" bson:"version"`
+	ProductsCount int                `json:"products_count" bson:"products_count"`
+	Active        bool               `json:"active" bson:"-"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
 type Product struct {
 	ExtID      string                 `json:"ext_id" bson:"ext_id"`
 	Name       string                 `json:"name" bson:"name"`
+	Category   string                 `json:"category,omitempty" bson:"category,omitempty"`
 	Price      float64                `json:"price" bson:"price"`
 	PriceOld   float64                `json:"price_old,omitempty" bson:"price_old,omitempty"`
 	Status     string                 `json:"status" bson:"status"`
 	Attributes map[string]interface{} `json:"attributes,omitempty" bson:"attributes,omitempty"`
+	// MissingFromSheet marks products a merge deleted because they were no
+	// longer in the sheet, as opposed to ones an operator deleted
+	MissingFromSheet bool `json:"-" bson:"missing_from_sheet,omitempty"`
+	// ExtIDGenerated marks IDs derived from the product key rather than read
+	// from the sheet; such IDs may be replaced by ones from a previous menu
+	ExtIDGenerated bool `json:"-" bson:"-"`
 }
 
 type AttributesGroup struct {
@@ -38,5 +59,3 @@ type Attribute struct {
 	Name  string `json:"name" bson:"name"`
 	Value string `json:"value,omitempty" bson:"value,omitempty"`
 }
-
-
@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MenuDiff describes what changed between a base menu and a target menu
+type MenuDiff struct {
+	BaseMenuID                *primitive.ObjectID       `json:"base_menu_id,omitempty" bson:"base_menu_id,omitempty"`
+	TargetMenuID              *primitive.ObjectID       `json:"target_menu_id,omitempty" bson:"target_menu_id,omitempty"`
+	ProductsAdded             []Product                 `json:"products_added" bson:"products_added"`
+	ProductsRemoved           []Product                 `json:"products_removed" bson:"products_removed"`
+	ProductsRenamed           []ProductRename           `json:"products_renamed" bson:"products_renamed"`
+	ProductsRepriced          []ProductReprice          `json:"products_repriced" bson:"products_repriced"`
+	ProductsAttributesChanged []ProductAttributesChange `json:"products_attributes_changed" bson:"products_attributes_changed"`
+	AttributesAdded           []Attribute               `json:"attributes_added" bson:"attributes_added"`
+	AttributesRemoved         []Attribute               `json:"attributes_removed" bson:"attributes_removed"`
+	AttributesGroupsAdded     []AttributesGroup         `json:"attributes_groups_added" bson:"attributes_groups_added"`
+	AttributesGroupsRemoved   []AttributesGroup         `json:"attributes_groups_removed" bson:"attributes_groups_removed"`
+	AttributesGroupsChanged   []AttributesGroupChange   `json:"attributes_groups_changed" bson:"attributes_groups_changed"`
+}
+
+type ProductRename struct {
+	ExtID   string `json:"ext_id" bson:"ext_id"`
+	OldName string `json:"old_name" bson:"old_name"`
+	NewName string `json:"new_name" bson:"new_name"`
+}
+
+type ProductReprice struct {
+	ExtID       string  `json:"ext_id" bson:"ext_id"`
+	Name        string  `json:"name" bson:"name"`
+	OldPrice    float64 `json:"old_price" bson:"old_price"`
+	NewPrice    float64 `json:"new_price" bson:"new_price"`
+	OldPriceOld float64 `json:"old_price_old,omitempty" bson:"old_price_old,omitempty"`
+	NewPriceOld float64 `json:"new_price_old,omitempty" bson:"new_price_old,omitempty"`
+}
+
+type ProductAttributesChange struct {
+	ExtID         string                 `json:"ext_id" bson:"ext_id"`
+	Name          string                 `json:"name" bson:"name"`
+	OldAttributes map[string]interface{} `json:"old_attributes,omitempty" bson:"old_attributes,omitempty"`
+	NewAttributes map[string]interface{} `json:"new_attributes,omitempty" bson:"new_attributes,omitempty"`
+}
+
+type AttributesGroupChange struct {
+	Name              string      `json:"name" bson:"name"`
+	AttributesAdded   []Attribute `json:"attributes_added" bson:"attributes_added"`
+	AttributesRemoved []Attribute `json:"attributes_removed" bson:"attributes_removed"`
+	OldIsRequired     bool        `json:"old_is_required" bson:"old_is_required"`
+	NewIsRequired     bool        `json:"new_is_required" bson:"new_is_required"`
+}
+
+// MenuDiffSummary counts the entries of a MenuDiff
+type MenuDiffSummary struct {
+	ProductsAdded             int `json:"products_added"`
+	ProductsRemoved           int `json:"products_removed"`
+	ProductsRenamed           int `json:"products_renamed"`
+	ProductsRepriced          int `json:"products_repriced"`
+	ProductsAttributesChanged int `json:"products_attributes_changed"`
+	AttributesAdded           int `json:"attributes_added"`
+	AttributesRemoved         int `json:"attributes_removed"`
+	AttributesGroupsAdded     int `json:"attributes_groups_added"`
+	AttributesGroupsRemoved   int `json:"attributes_groups_removed"`
+	AttributesGroupsChanged   int `json:"attributes_groups_changed"`
+}
+
+func (d *MenuDiff) Summary() MenuDiffSummary {
+	return MenuDiffSummary{
+		ProductsAdded:             len(d.ProductsAdded),
+		ProductsRemoved:           len(d.ProductsRemoved),
+		ProductsRenamed:           len(d.ProductsRenamed),
+		ProductsRepriced:          len(d.ProductsRepriced),
+		ProductsAttributesChanged: len(d.ProductsAttributesChanged),
+		AttributesAdded:           len(d.AttributesAdded),
+		AttributesRemoved:         len(d.AttributesRemoved),
+		AttributesGroupsAdded:     len(d.AttributesGroupsAdded),
+		AttributesGroupsRemoved:   len(d.AttributesGroupsRemoved),
+		AttributesGroupsChanged:   len(d.AttributesGroupsChanged),
+	}
+}
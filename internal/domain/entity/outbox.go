@@ -0,0 +1,83 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OutboxMessageType string
+
+const (
+	OutboxMessageMenuParsingTask    OutboxMessageType = "menu_parsing_task"
+	OutboxMessageProductStatusEvent OutboxMessageType = "product_status_event"
+	// OutboxMessageTaskWebhook is delivered over HTTP instead of the queue
+	OutboxMessageTaskWebhook OutboxMessageType = "task_webhook"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending   OutboxStatus = "pending"
+	OutboxStatusPublished OutboxStatus = "published"
+	OutboxStatusFailed    OutboxStatus = "failed"
+)
+
+// OutboxMessage is a queue message stored in Mongo together with the change
+// that produced it and published later by the outbox relay. AvailableAt holds
+// both the relay's lease on a claimed message and the retry backoff.
+type OutboxMessage struct {
+	ID          primitive.ObjectID        `json:"_id" bson:"_id,omitempty"`
+	Type        OutboxMessageType         `json:"type" bson:"type"`
+	TaskID      string                    `json:"task_id,omitempty" bson:"task_id,omitempty"`
+	Event       *ProductStatusChangeEvent `json:"event,omitempty" bson:"event,omitempty"`
+	Webhook     *TaskWebhook              `json:"webhook,omitempty" bson:"webhook,omitempty"`
+	Status      OutboxStatus              `json:"status" bson:"status"`
+	Attempts    int                       `json:"attempts" bson:"attempts"`
+	LastError   string                    `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	AvailableAt time.Time                 `json:"available_at" bson:"available_at"`
+	CreatedAt   time.Time                 `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time                 `json:"updated_at" bson:"updated_at"`
+	PublishedAt *time.Time                `json:"published_at,omitempty" bson:"published_at,omitempty"`
+}
+
+// NewMenuParsingOutboxMessage creates a pending message that queues a parsing task
+func NewMenuParsingOutboxMessage(taskID string) *OutboxMessage {
+	now := time.Now()
+	return &OutboxMessage{
+		Type:        OutboxMessageMenuParsingTask,
+		TaskID:      taskID,
+		Status:      OutboxStatusPending,
+		AvailableAt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// NewProductStatusOutboxMessage creates a pending message that queues a product status event
+func NewProductStatusOutboxMessage(event *ProductStatusChangeEvent) *OutboxMessage {
+	now := time.Now()
+	return &OutboxMessage{
+		Type:        OutboxMessageProductStatusEvent,
+		Event:       event,
+		Status:      OutboxStatusPending,
+		AvailableAt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// NewTaskWebhookOutboxMessage creates a pending message that notifies the
+// callback URL of a finished task
+func NewTaskWebhookOutboxMessage(task *ParsingTask) *OutboxMessage {
+	now := time.Now()
+	return &OutboxMessage{
+		Type:        OutboxMessageTaskWebhook,
+		TaskID:      task.ID,
+		Webhook:     NewTaskWebhook(task),
+		Status:      OutboxStatusPending,
+		AvailableAt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
@@ -13,18 +13,56 @@ const (
 	TaskStatusProcessing ParsingTaskStatus = "processing"
 	TaskStatusCompleted  ParsingTaskStatus = "completed"
 	TaskStatusFailed     ParsingTaskStatus = "failed"
+	// TaskStatusCancelled is final until the task is retried by hand
+	TaskStatusCancelled ParsingTaskStatus = "cancelled"
+)
+
+// ParseMode controls how a parsed menu is stored
+type ParseMode string
+
+const (
+	// ParseModeCreate stores the parsed menu as a new document
+	ParseModeCreate ParseMode = "create"
+	// ParseModeMerge updates the restaurant's existing menu in place
+	ParseModeMerge ParseMode = "merge"
 )
 
 type ParsingTask struct {
 	ID             string              `json:"task_id" bson:"_id"`
 	Status         ParsingTaskStatus   `json:"status" bson:"status"`
 	SpreadsheetID  string              `json:"spreadsheet_id" bson:"spreadsheet_id"`
+	RestaurantID   string              `json:"restaurant_id,omitempty" bson:"restaurant_id,omitempty"`
 	RestaurantName string              `json:"restaurant_name" bson:"restaurant_name"`
+	ColumnMapping  *ColumnMapping      `json:"column_mapping,omitempty" bson:"column_mapping,omitempty"`
+	MappingProfile string              `json:"mapping_profile,omitempty" bson:"mapping_profile,omitempty"`
+	Sheets         []string            `json:"sheets,omitempty" bson:"sheets,omitempty"`
+	AllSheets      bool                `json:"all_sheets,omitempty" bson:"all_sheets,omitempty"`
+	Mode           ParseMode           `json:"mode,omitempty" bson:"mode,omitempty"`
+	SkipActivation bool                `json:"skip_activation,omitempty" bson:"skip_activation,omitempty"`
 	MenuID         *primitive.ObjectID `json:"menu_id,omitempty" bson:"menu_id,omitempty"`
+	ErrorCode      string              `json:"error_code,omitempty" bson:"error_code,omitempty"`
 	ErrorMessage   string              `json:"error,omitempty" bson:"error_message,omitempty"`
+	Diff           *MenuDiff           `json:"diff,omitempty" bson:"diff,omitempty"`
 	RetryCount     int                 `json:"retry_count" bson:"retry_count"`
-	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
-	UpdatedAt      time.Time           `json:"updated_at" bson:"updated_at"`
+	// CallbackURL is notified once the task completed or failed for good
+	CallbackURL       string            `json:"callback_url,omitempty" bson:"callback_url,omitempty"`
+	WebhookDeliveries []WebhookDelivery `json:"webhook_deliveries,omitempty" bson:"webhook_deliveries,omitempty"`
+	// CreatedBy is the authenticated caller that requested the parse
+	CreatedBy string `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	// RetriedBy is who last requeued the failed or cancelled task by hand
+	RetriedBy   string     `json:"retried_by,omitempty" bson:"retried_by,omitempty"`
+	RetriedAt   *time.Time `json:"retried_at,omitempty" bson:"retried_at,omitempty"`
+	CancelledBy string     `json:"cancelled_by,omitempty" bson:"cancelled_by,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty" bson:"cancelled_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" bson:"updated_at"`
 }
 
-
+// RestaurantKey identifies the restaurant menus of this task belong to. Tasks
+// created before restaurants were registered fall back to the free-text name.
+func (t *ParsingTask) RestaurantKey() string {
+	if t.RestaurantID != "" {
+		return t.RestaurantID
+	}
+	return t.RestaurantName
+}
@@ -0,0 +1,57 @@
+package entity
+
+import "slices"
+
+type Role string
+
+const (
+	// RoleAdmin may do everything, for every restaurant
+	RoleAdmin Role = "admin"
+	// RoleOperator manages menus and stop-lists of its restaurants
+	RoleOperator Role = "operator"
+	// RoleReadOnly may only read data of its restaurants
+	RoleReadOnly Role = "readonly"
+)
+
+// AllRestaurants in Principal.RestaurantIDs grants access to every restaurant
+const AllRestaurants = "*"
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleOperator, RoleReadOnly:
+		return true
+	}
+	return false
+}
+
+// Principal is the authenticated caller of the API
+type Principal struct {
+	// Subject identifies the caller in audit records and parsing tasks
+	Subject string
+	Role    Role
+	// RestaurantIDs scopes operators and read-only callers; admins are not scoped
+	RestaurantIDs []string
+}
+
+func (p *Principal) IsAdmin() bool {
+	return p.Role == RoleAdmin
+}
+
+// Unscoped reports whether the principal may read every restaurant
+func (p *Principal) Unscoped() bool {
+	return p.IsAdmin() || slices.Contains(p.RestaurantIDs, AllRestaurants)
+}
+
+// CanRead reports whether the principal may read data of the restaurant
+func (p *Principal) CanRead(restaurantID string) bool {
+	return p.IsAdmin() || (p.Role.Valid() && p.hasRestaurant(restaurantID))
+}
+
+// CanWrite reports whether the principal may change data of the restaurant
+func (p *Principal) CanWrite(restaurantID string) bool {
+	return p.IsAdmin() || (p.Role == RoleOperator && p.hasRestaurant(restaurantID))
+}
+
+func (p *Principal) hasRestaurant(restaurantID string) bool {
+	return slices.Contains(p.RestaurantIDs, AllRestaurants) || slices.Contains(p.RestaurantIDs, restaurantID)
+}
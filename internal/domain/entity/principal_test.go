@@ -0,0 +1,50 @@
+package entity
+
+import "testing"
+
+func TestPrincipalAccess(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		canRead   bool
+		canWrite  bool
+		unscoped  bool
+	}{
+		{"admin", Principal{Role: RoleAdmin}, true, true, true},
+		{"admin scoped to another restaurant", Principal{Role: RoleAdmin, RestaurantIDs: []string{"rest-2"}}, true, true, true},
+		{"operator of the restaurant", Principal{Role: RoleOperator, RestaurantIDs: []string{"rest-2", "rest-1"}}, true, true, false},
+		{"operator of another restaurant", Principal{Role: RoleOperator, RestaurantIDs: []string{"rest-2"}}, false, false, false},
+		{"operator of all restaurants", Principal{Role: RoleOperator, RestaurantIDs: []string{AllRestaurants}}, true, true, true},
+		{"operator without restaurants", Principal{Role: RoleOperator}, false, false, false},
+		{"readonly of the restaurant", Principal{Role: RoleReadOnly, RestaurantIDs: []string{"rest-1"}}, true, false, false},
+		{"readonly of another restaurant", Principal{Role: RoleReadOnly, RestaurantIDs: []string{"rest-2"}}, false, false, false},
+		{"readonly of all restaurants", Principal{Role: RoleReadOnly, RestaurantIDs: []string{AllRestaurants}}, true, false, true},
+		{"unknown role", Principal{Role: "owner", RestaurantIDs: []string{"rest-1"}}, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.CanRead("rest-1"); got != tt.canRead {
+				t.Errorf("CanRead = %v, want %v", got, tt.canRead)
+			}
+			if got := tt.principal.CanWrite("rest-1"); got != tt.canWrite {
+				t.Errorf("CanWrite = %v, want %v", got, tt.canWrite)
+			}
+			if got := tt.principal.Unscoped(); got != tt.unscoped {
+				t.Errorf("Unscoped = %v, want %v", got, tt.unscoped)
+			}
+		})
+	}
+}
+
+func TestRoleValid(t *testing.T) {
+	for _, role := range []Role{RoleAdmin, RoleOperator, RoleReadOnly} {
+		if !role.Valid() {
+			t.Errorf("role %q is not valid", role)
+		}
+	}
+	for _, role := range []Role{"", "Admin", "owner", "superuser"} {
+		if role.Valid() {
+			t.Errorf("role %q is valid", role)
+		}
+	}
+}
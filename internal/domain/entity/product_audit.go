@@ -23,18 +23,26 @@ const (
 	ProductStatusDeleted      ProductStatus = "deleted"
 )
 
+// ProductStatusAudit is one change of a product. CorrelationID links the record
+// to its cause: the status change event ID, or for a merge the parsing task ID
+// and the menu revision it was merged onto ("<task_id>:<revision>").
 type ProductStatusAudit struct {
-	ID        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
-	ProductID string             `json:"product_id" bson:"product_id"`
-	EventType ProductEventType   `json:"event_type" bson:"event_type"`
-	OldStatus string             `json:"old_status" bson:"old_status"`
-	NewStatus string             `json:"new_status" bson:"new_status"`
-	Reason    string             `json:"reason" bson:"reason"`
-	UserID    string             `json:"user_id" bson:"user_id"`
-	Timestamp time.Time          `json:"timestamp" bson:"timestamp"`
+	ID            primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	RestaurantID  string             `json:"restaurant_id,omitempty" bson:"restaurant_id,omitempty"`
+	MenuID        primitive.ObjectID `json:"menu_id,omitempty" bson:"menu_id,omitempty"`
+	ProductID     string             `json:"product_id" bson:"product_id"`
+	ProductName   string             `json:"product_name,omitempty" bson:"product_name,omitempty"`
+	CorrelationID string             `json:"correlation_id,omitempty" bson:"correlation_id,omitempty"`
+	EventType     ProductEventType   `json:"event_type" bson:"event_type"`
+	OldStatus     string             `json:"old_status" bson:"old_status"`
+	NewStatus     string             `json:"new_status" bson:"new_status"`
+	Reason        string             `json:"reason" bson:"reason"`
+	UserID        string             `json:"user_id" bson:"user_id"`
+	Timestamp     time.Time          `json:"timestamp" bson:"timestamp"`
 }
 
 type ProductStatusChangeEvent struct {
+	EventID      string           `json:"event_id"`
 	EventType    ProductEventType `json:"event_type"`
 	RestaurantID string           `json:"restaurant_id"`
 	ProductID    string           `json:"product_id"`
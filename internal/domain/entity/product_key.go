@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+)
+
+// ProductKey builds the normalized identity of a product from its category
+// and name, so that whitespace and case edits in the sheet do not change it
+func ProductKey(category, name string) string {
+	return normalizeKeyPart(category) + "/" + normalizeKeyPart(name)
+}
+
+// ProductExtIDFromKey derives a deterministic ExtID from a product key
+func ProductExtIDFromKey(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return "p" + hex.EncodeToString(sum[:])[:12]
+}
+
+func normalizeKeyPart(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+// Restaurant is the stable identity that menus, tasks and audit records refer to
+type Restaurant struct {
+	ID            string             `json:"id" bson:"_id"`
+	Name          string             `json:"name" bson:"name"`
+	NameKey       string             `json:"-" bson:"name_key"`
+	SpreadsheetID string             `json:"spreadsheet_id,omitempty" bson:"spreadsheet_id,omitempty"`
+	Timezone      string             `json:"timezone" bson:"timezone"`
+	Settings      RestaurantSettings `json:"settings" bson:"settings"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// RestaurantSettings holds parse defaults applied when a request does not override them
+type RestaurantSettings struct {
+	MappingProfile string    `json:"mapping_profile,omitempty" bson:"mapping_profile,omitempty"`
+	Sheets         []string  `json:"sheets,omitempty" bson:"sheets,omitempty"`
+	AllSheets      bool      `json:"all_sheets,omitempty" bson:"all_sheets,omitempty"`
+	ParseMode      ParseMode `json:"parse_mode,omitempty" bson:"parse_mode,omitempty"`
+}
+
+// RestaurantNameKey normalizes a display name so that case and spacing
+// variants of the same name are treated as one restaurant
+func RestaurantNameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
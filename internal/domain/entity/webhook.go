@@ -0,0 +1,45 @@
+package entity
+
+import "time"
+
+// TaskWebhook is the callback of a finished parsing task, captured when the
+// task finished so that a later retry of the task does not change it
+type TaskWebhook struct {
+	URL     string             `json:"url" bson:"url"`
+	Payload TaskWebhookPayload `json:"payload" bson:"payload"`
+}
+
+// TaskWebhookPayload is the JSON body POSTed to the callback URL
+type TaskWebhookPayload struct {
+	TaskID     string            `json:"task_id" bson:"task_id"`
+	Status     ParsingTaskStatus `json:"status" bson:"status"`
+	MenuID     string            `json:"menu_id,omitempty" bson:"menu_id,omitempty"`
+	ErrorCode  string            `json:"error_code,omitempty" bson:"error_code,omitempty"`
+	Error      string            `json:"error,omitempty" bson:"error,omitempty"`
+	FinishedAt time.Time         `json:"finished_at" bson:"finished_at"`
+}
+
+// WebhookDelivery records one attempt to call the callback URL of a task
+type WebhookDelivery struct {
+	// DeliveryID is the same for all attempts of one notification
+	DeliveryID  string    `json:"delivery_id" bson:"delivery_id"`
+	Attempt     int       `json:"attempt" bson:"attempt"`
+	StatusCode  int       `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty" bson:"error,omitempty"`
+	AttemptedAt time.Time `json:"attempted_at" bson:"attempted_at"`
+}
+
+// NewTaskWebhook captures the outcome of the task for its callback URL
+func NewTaskWebhook(task *ParsingTask) *TaskWebhook {
+	payload := TaskWebhookPayload{
+		TaskID:     task.ID,
+		Status:     task.Status,
+		ErrorCode:  task.ErrorCode,
+		Error:      task.ErrorMessage,
+		FinishedAt: time.Now(),
+	}
+	if task.MenuID != nil {
+		payload.MenuID = task.MenuID.Hex()
+	}
+	return &TaskWebhook{URL: task.CallbackURL, Payload: payload}
+}
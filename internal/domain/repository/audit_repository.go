@@ -2,12 +2,37 @@ package repository
 
 import (
 	"context"
+	"time"
 
+	"menu-parser/internal/domain/apperror"
 	"menu-parser/internal/domain/entity"
 )
 
+// ErrAuditRecordExists is returned when a record for the same correlation ID, product and event type exists
+var ErrAuditRecordExists = apperror.New(apperror.KindConflict, "audit_record_exists", "audit record already exists")
+
+// AuditFilter narrows an audit query; zero values match everything. Results
+// are returned newest first and Cursor continues after the last returned record.
+type AuditFilter struct {
+	RestaurantID string
+	ProductID    string
+	UserID       string
+	Status       string
+	EventType    entity.ProductEventType
+	From         time.Time
+	To           time.Time
+	Cursor       string
+	Limit        int
+}
+
 type AuditRepository interface {
 	Create(ctx context.Context, audit *entity.ProductStatusAudit) error
+	CreateMany(ctx context.Context, audits []entity.ProductStatusAudit) error
+	ExistsByCorrelationID(ctx context.Context, correlationID, productID string) (bool, error)
+	// List returns a page of audit records and the cursor of the next page, empty on the last page
+	List(ctx context.Context, filter AuditFilter) ([]entity.ProductStatusAudit, string, error)
+	// ListWithoutRestaurant returns records written before restaurant_id was recorded, in insertion order after the given ID
+	ListWithoutRestaurant(ctx context.Context, afterID string, limit int) ([]entity.ProductStatusAudit, error)
+	// UpdateContext fills in restaurant, menu and product name of a record that has no restaurant yet
+	UpdateContext(ctx context.Context, audit *entity.ProductStatusAudit) error
 }
-
-
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"menu-parser/internal/domain/apperror"
+	"menu-parser/internal/domain/entity"
+)
+
+// ErrColumnMappingProfileNotFound is returned when a restaurant has no profile with the requested name
+var ErrColumnMappingProfileNotFound = apperror.New(apperror.KindNotFound, "column_mapping_profile_not_found", "column mapping profile not found")
+
+type ColumnMappingRepository interface {
+	Upsert(ctx context.Context, profile *entity.ColumnMappingProfile) (*entity.ColumnMappingProfile, error)
+	GetByName(ctx context.Context, restaurantID, name string) (*entity.ColumnMappingProfile, error)
+	ListByRestaurant(ctx context.Context, restaurantID string) ([]entity.ColumnMappingProfile, error)
+	Delete(ctx context.Context, restaurantID, name string) error
+}
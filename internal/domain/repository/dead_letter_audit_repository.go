@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"context"
+
+	"menu-parser/internal/domain/entity"
+)
+
+type DeadLetterAuditRepository interface {
+	CreateMany(ctx context.Context, records []entity.DeadLetterAudit) error
+}
@@ -3,12 +3,35 @@ package repository
 import (
 	"context"
 
+	"menu-parser/internal/domain/apperror"
 	"menu-parser/internal/domain/entity"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrMenuNotFound is returned when no menu matches the request
+	ErrMenuNotFound = apperror.New(apperror.KindNotFound, "menu_not_found", "menu not found")
+	// ErrProductNotFound is returned when the restaurant's active menu has no product with the requested ext ID
+	ErrProductNotFound = apperror.New(apperror.KindNotFound, "product_not_found", "product not found")
+	// ErrMenuModified is returned when a menu changed after it was read. It is
+	// transient: writing again on top of a fresh read succeeds.
+	ErrMenuModified = apperror.New(apperror.KindUnavailable, "menu_modified", "menu was modified concurrently")
 )
 
 type MenuRepository interface {
 	Create(ctx context.Context, menu *entity.Menu) (*entity.Menu, error)
+	// Update replaces the sheet-driven content of a menu, provided its
+	// revision is still the one read; otherwise it returns ErrMenuModified
+	Update(ctx context.Context, menu *entity.Menu) error
 	GetByID(ctx context.Context, menuID string) (*entity.Menu, error)
+	// GetActiveByRestaurant returns nil without an error when the restaurant has no menus
+	GetActiveByRestaurant(ctx context.Context, restaurantID string) (*entity.Menu, error)
+	ListVersions(ctx context.Context, restaurantID string) ([]entity.MenuVersion, error)
+	Activate(ctx context.Context, restaurantID, menuID string) (*entity.Menu, error)
 	GetProductStatus(ctx context.Context, restaurantID, productID string) (string, error)
-	UpdateProductStatus(ctx context.Context, restaurantID, productID, newStatus string) (string, error)
+	// UpdateProductStatus returns the ID of the updated menu and the product as it was before the update
+	UpdateProductStatus(ctx context.Context, restaurantID, productID, newStatus string) (primitive.ObjectID, *entity.Product, error)
+	// FindByProduct returns menus of any restaurant containing the product, with only that product projected
+	FindByProduct(ctx context.Context, productID string) ([]entity.Menu, error)
 }
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OutboxRepository interface {
+	Create(ctx context.Context, message *entity.OutboxMessage) error
+	// ClaimNext leases the oldest pending message that is due and increments
+	// its attempt counter; it returns nil without an error when none is due
+	ClaimNext(ctx context.Context, lease time.Duration) (*entity.OutboxMessage, error)
+	MarkPublished(ctx context.Context, id primitive.ObjectID) error
+	// ScheduleRetry releases a claimed message and makes it due again at the given time
+	ScheduleRetry(ctx context.Context, id primitive.ObjectID, lastError string, availableAt time.Time) error
+	MarkFailed(ctx context.Context, id primitive.ObjectID, lastError string) error
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"menu-parser/internal/domain/apperror"
+	"menu-parser/internal/domain/entity"
+)
+
+var (
+	// ErrRestaurantNotFound is returned when no restaurant has the requested ID
+	ErrRestaurantNotFound = apperror.New(apperror.KindNotFound, "restaurant_not_found", "restaurant not found")
+	// ErrRestaurantExists is returned when another restaurant already uses the same name
+	ErrRestaurantExists = apperror.New(apperror.KindConflict, "restaurant_exists", "restaurant with this name already exists")
+)
+
+type RestaurantRepository interface {
+	Create(ctx context.Context, restaurant *entity.Restaurant) error
+	GetByID(ctx context.Context, restaurantID string) (*entity.Restaurant, error)
+	List(ctx context.Context) ([]entity.Restaurant, error)
+	Update(ctx context.Context, restaurant *entity.Restaurant) error
+	Delete(ctx context.Context, restaurantID string) error
+}
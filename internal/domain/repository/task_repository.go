@@ -2,17 +2,69 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"menu-parser/internal/domain/apperror"
 	"menu-parser/internal/domain/entity"
 )
 
+// ErrTaskNotFound is returned when no parsing task has the requested ID
+var ErrTaskNotFound = apperror.New(apperror.KindNotFound, "task_not_found", "task not found")
+
+// TaskSortField is the timestamp parsing tasks are listed by
+type TaskSortField string
+
+const (
+	TaskSortCreatedAt TaskSortField = "created_at"
+	TaskSortUpdatedAt TaskSortField = "updated_at"
+)
+
+// TaskFilter narrows a task listing; zero values match everything.
+// RestaurantKeys matches tasks by RestaurantKey, a nil slice matches every
+// restaurant and an empty one none. Cursor continues after the last returned task.
+type TaskFilter struct {
+	Status         entity.ParsingTaskStatus
+	RestaurantKeys []string
+	SpreadsheetID  string
+	CreatedFrom    time.Time
+	CreatedTo      time.Time
+	SortBy         TaskSortField
+	Ascending      bool
+	Cursor         string
+	Limit          int
+}
+
 type TaskRepository interface {
 	Create(ctx context.Context, task *entity.ParsingTask) error
 	GetByID(ctx context.Context, taskID string) (*entity.ParsingTask, error)
-	UpdateStatus(ctx context.Context, taskID string, status entity.ParsingTaskStatus, menuID *primitive.ObjectID, errorMsg string) error
+	// List returns a page of tasks and the cursor of the next page, empty on the last page
+	List(ctx context.Context, filter TaskFilter) ([]entity.ParsingTask, string, error)
+	// UpdateStatus, MarkFailed and RecordError leave cancelled tasks unchanged;
+	// UpdateStatus and MarkFailed report whether they changed the task
+	UpdateStatus(ctx context.Context, taskID string, status entity.ParsingTaskStatus, menuID *primitive.ObjectID, errorMsg string) (bool, error)
+	// MarkFailed sets the task failed with a machine-readable code and a message
+	MarkFailed(ctx context.Context, taskID, errorCode, errorMsg string) (bool, error)
+	// RecordError stores the code and message of an error without changing the task status
+	RecordError(ctx context.Context, taskID, errorCode, errorMsg string) error
 	IncrementRetryCount(ctx context.Context, taskID string) error
+	SaveDiff(ctx context.Context, taskID string, diff *entity.MenuDiff) error
+	// ClaimForProcessing moves a queued task to processing and reports whether it did
+	ClaimForProcessing(ctx context.Context, taskID string) (bool, error)
+	// FindStale returns queued or processing tasks last updated before the given time
+	FindStale(ctx context.Context, updatedBefore time.Time, limit int) ([]entity.ParsingTask, error)
+	// RequeueStale and FailStale change a stale task only if it is still in the
+	// state it was read in, and report whether they did
+	RequeueStale(ctx context.Context, task *entity.ParsingTask) (bool, error)
+	FailStale(ctx context.Context, task *entity.ParsingTask, errorCode, reason string) (bool, error)
+	// RequeueFailed moves a failed task back to queued with a fresh retry
+	// budget and no error, and reports whether it did
+	RequeueFailed(ctx context.Context, taskID, requestedBy string) (bool, error)
+	// RequeueFailedOrCancelled does the same for a failed or cancelled task
+	RequeueFailedOrCancelled(ctx context.Context, taskID, requestedBy string) (bool, error)
+	// RecordWebhookDelivery appends an attempt to notify the task's callback URL
+	RecordWebhookDelivery(ctx context.Context, taskID string, delivery *entity.WebhookDelivery) error
+	// Cancel moves a queued or processing task to cancelled and reports whether it did
+	Cancel(ctx context.Context, taskID, requestedBy string) (bool, error)
 }
-
-
@@ -0,0 +1,9 @@
+package repository
+
+import "context"
+
+// Transactor runs fn so that all repository writes made with the context it
+// receives are committed together or not at all
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
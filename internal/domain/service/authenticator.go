@@ -0,0 +1,10 @@
+package service
+
+import "menu-parser/internal/domain/entity"
+
+// Authenticator resolves the credentials of an API request to a principal
+type Authenticator interface {
+	AuthenticateAPIKey(apiKey string) (*entity.Principal, error)
+	// AuthenticateToken verifies a bearer JWT
+	AuthenticateToken(token string) (*entity.Principal, error)
+}
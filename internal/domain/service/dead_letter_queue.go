@@ -0,0 +1,15 @@
+package service
+
+import "menu-parser/internal/domain/entity"
+
+// DeadLetterQueue inspects and drains the dead-letter queue. Every operation
+// looks at up to limit messages from the head of the queue; messages that are
+// not selected stay in the queue.
+type DeadLetterQueue interface {
+	ListDeadLetters(limit int) ([]entity.DeadLetter, error)
+	// ReplayDeadLetters republishes the selected messages to their source queue
+	// and returns the replayed ones
+	ReplayDeadLetters(messageIDs []string, limit int) ([]entity.DeadLetter, error)
+	// PurgeDeadLetters drops the selected messages and returns the dropped ones
+	PurgeDeadLetters(messageIDs []string, limit int) ([]entity.DeadLetter, error)
+}
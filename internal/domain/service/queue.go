@@ -9,14 +9,24 @@ type QueuePublisher interface {
 	PublishProductStatusEvent(event *entity.ProductStatusChangeEvent) error
 }
 
+// Acknowledger settles a single delivery. A delivery is settled once; later
+// calls return an error.
+type Acknowledger interface {
+	Ack() error
+	Nack(requeue bool) error
+	// Retry schedules the message for redelivery after a backoff delay and
+	// acknowledges the current delivery
+	Retry() error
+}
+
 type Message struct {
-	Body        []byte
-	DeliveryTag uint64
+	Acknowledger
+	Body []byte
+	// Attempt is how many times the message has been retried, 0 on first delivery
+	Attempt int
 }
 
 type QueueConsumer interface {
 	ConsumeMenuParsingTasks() (<-chan Message, error)
 	ConsumeProductStatusEvents() (<-chan Message, error)
-	AckMessage(deliveryTag uint64) error
-	NackMessage(deliveryTag uint64, requeue bool) error
 }
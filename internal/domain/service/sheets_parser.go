@@ -6,8 +6,19 @@ import (
 	"menu-parser/internal/domain/entity"
 )
 
-type SheetsParser interface {
-	ParseMenu(ctx context.Context, spreadsheetID, restaurantName string) (*entity.Menu, error)
+// ParseMenuRequest describes a single spreadsheet parse
+type ParseMenuRequest struct {
+	SpreadsheetID  string
+	RestaurantID   string
+	RestaurantName string
+	// ColumnMapping overrides the default column layout when set
+	ColumnMapping *entity.ColumnMapping
+	// Sheets lists the tabs to parse; empty means the first tab only
+	Sheets []string
+	// AllSheets parses every tab and takes precedence over Sheets
+	AllSheets bool
 }
 
-
+type SheetsParser interface {
+	ParseMenu(ctx context.Context, req *ParseMenuRequest) (*entity.Menu, error)
+}
@@ -0,0 +1,9 @@
+package service
+
+import "context"
+
+// WebhookSender POSTs signed JSON payloads to callback URLs
+type WebhookSender interface {
+	// Send returns the HTTP status of the response; a status other than 2xx is an error
+	Send(ctx context.Context, url, deliveryID string, payload []byte) (int, error)
+}
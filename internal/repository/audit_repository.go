@@ -2,11 +2,15 @@ package repository
 
 import (
 	"context"
-	"fmt"
 
 	"menu-parser/internal/domain/entity"
 	"menu-parser/internal/domain/repository"
 	"menu-parser/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type AuditRepository struct {
@@ -20,9 +24,155 @@ func NewAuditRepository(db *database.MongoDB) repository.AuditRepository {
 func (r *AuditRepository) Create(ctx context.Context, audit *entity.ProductStatusAudit) error {
 	_, err := r.db.Database.Collection("product_status_audit").InsertOne(ctx, audit)
 	if err != nil {
-		return fmt.Errorf("failed to create audit record: %w", err)
+		if mongo.IsDuplicateKeyError(err) {
+			return repository.ErrAuditRecordExists
+		}
+		return databaseError(err, "failed to create audit record")
+	}
+	return nil
+}
+
+func (r *AuditRepository) ExistsByCorrelationID(ctx context.Context, correlationID, productID string) (bool, error) {
+	count, err := r.db.Database.Collection("product_status_audit").CountDocuments(
+		ctx,
+		bson.M{"correlation_id": correlationID, "product_id": productID},
+		options.Count().SetLimit(1),
+	)
+	if err != nil {
+		return false, databaseError(err, "failed to check audit record")
+	}
+	return count > 0, nil
+}
+
+func (r *AuditRepository) CreateMany(ctx context.Context, audits []entity.ProductStatusAudit) error {
+	if len(audits) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(audits))
+	for i := range audits {
+		docs[i] = audits[i]
+	}
+
+	_, err := r.db.Database.Collection("product_status_audit").InsertMany(ctx, docs)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return repository.ErrAuditRecordExists
+		}
+		return databaseError(err, "failed to create audit records")
 	}
 	return nil
 }
 
+func (r *AuditRepository) List(ctx context.Context, filter repository.AuditFilter) ([]entity.ProductStatusAudit, string, error) {
+	query := bson.M{}
+	if filter.RestaurantID != "" {
+		query["restaurant_id"] = filter.RestaurantID
+	}
+	if filter.ProductID != "" {
+		query["product_id"] = filter.ProductID
+	}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Status != "" {
+		query["new_status"] = filter.Status
+	}
+	if filter.EventType != "" {
+		query["event_type"] = filter.EventType
+	}
+
+	timestamp := bson.M{}
+	if !filter.From.IsZero() {
+		timestamp["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		timestamp["$lte"] = filter.To
+	}
+	if len(timestamp) > 0 {
+		query["timestamp"] = timestamp
+	}
+
+	if filter.Cursor != "" {
+		after, err := primitive.ObjectIDFromHex(filter.Cursor)
+		if err != nil {
+			return nil, "", invalidIDError(err, "invalid cursor")
+		}
+		query["_id"] = bson.M{"$lt": after}
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}})
+	if filter.Limit > 0 {
+		// One extra record tells whether another page exists
+		findOptions.SetLimit(int64(filter.Limit + 1))
+	}
+
+	cursor, err := r.db.Database.Collection("product_status_audit").Find(ctx, query, findOptions)
+	if err != nil {
+		return nil, "", databaseError(err, "failed to list audit records")
+	}
+	defer cursor.Close(ctx)
+
+	audits := []entity.ProductStatusAudit{}
+	if err := cursor.All(ctx, &audits); err != nil {
+		return nil, "", databaseError(err, "failed to decode audit records")
+	}
+
+	nextCursor := ""
+	if filter.Limit > 0 && len(audits) > filter.Limit {
+		audits = audits[:filter.Limit]
+		nextCursor = audits[len(audits)-1].ID.Hex()
+	}
+
+	return audits, nextCursor, nil
+}
+
+func (r *AuditRepository) ListWithoutRestaurant(ctx context.Context, afterID string, limit int) ([]entity.ProductStatusAudit, error) {
+	query := bson.M{"restaurant_id": bson.M{"$in": bson.A{nil, ""}}}
+	if afterID != "" {
+		after, err := primitive.ObjectIDFromHex(afterID)
+		if err != nil {
+			return nil, invalidIDError(err, "invalid audit ID")
+		}
+		query["_id"] = bson.M{"$gt": after}
+	}
+
+	cursor, err := r.db.Database.Collection("product_status_audit").Find(
+		ctx,
+		query,
+		options.Find().
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, databaseError(err, "failed to list audit records")
+	}
+	defer cursor.Close(ctx)
+
+	audits := []entity.ProductStatusAudit{}
+	if err := cursor.All(ctx, &audits); err != nil {
+		return nil, databaseError(err, "failed to decode audit records")
+	}
+
+	return audits, nil
+}
+
+func (r *AuditRepository) UpdateContext(ctx context.Context, audit *entity.ProductStatusAudit) error {
+	set := bson.M{"restaurant_id": audit.RestaurantID}
+	if !audit.MenuID.IsZero() {
+		set["menu_id"] = audit.MenuID
+	}
+	if audit.ProductName != "" {
+		set["product_name"] = audit.ProductName
+	}
 
+	_, err := r.db.Database.Collection("product_status_audit").UpdateOne(
+		ctx,
+		bson.M{"_id": audit.ID, "restaurant_id": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		return databaseError(err, "failed to update audit record")
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+	"menu-parser/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ColumnMappingRepository struct {
+	db *database.MongoDB
+}
+
+func NewColumnMappingRepository(db *database.MongoDB) repository.ColumnMappingRepository {
+	return &ColumnMappingRepository{db: db}
+}
+
+func (r *ColumnMappingRepository) Upsert(ctx context.Context, profile *entity.ColumnMappingProfile) (*entity.ColumnMappingProfile, error) {
+	now := time.Now()
+	filter := bson.M{
+		"restaurant_id": profile.RestaurantID,
+		"name":          profile.Name,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"mapping":    profile.Mapping,
+			"updated_at": now,
+		},
+		"$setOnInsert": bson.M{
+			"created_at": now,
+		},
+	}
+
+	var saved entity.ColumnMappingProfile
+	err := r.db.Database.Collection("column_mappings").FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&saved)
+	if err != nil {
+		return nil, databaseError(err, "failed to save column mapping profile")
+	}
+
+	return &saved, nil
+}
+
+func (r *ColumnMappingRepository) GetByName(ctx context.Context, restaurantID, name string) (*entity.ColumnMappingProfile, error) {
+	var profile entity.ColumnMappingProfile
+	filter := bson.M{
+		"restaurant_id": restaurantID,
+		"name":          name,
+	}
+
+	err := r.db.Database.Collection("column_mappings").FindOne(ctx, filter).Decode(&profile)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrColumnMappingProfileNotFound
+		}
+		return nil, databaseError(err, "failed to get column mapping profile")
+	}
+
+	return &profile, nil
+}
+
+func (r *ColumnMappingRepository) ListByRestaurant(ctx context.Context, restaurantID string) ([]entity.ColumnMappingProfile, error) {
+	cursor, err := r.db.Database.Collection("column_mappings").Find(
+		ctx,
+		bson.M{"restaurant_id": restaurantID},
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}}),
+	)
+	if err != nil {
+		return nil, databaseError(err, "failed to list column mapping profiles")
+	}
+	defer cursor.Close(ctx)
+
+	profiles := []entity.ColumnMappingProfile{}
+	if err := cursor.All(ctx, &profiles); err != nil {
+		return nil, databaseError(err, "failed to decode column mapping profiles")
+	}
+
+	return profiles, nil
+}
+
+func (r *ColumnMappingRepository) Delete(ctx context.Context, restaurantID, name string) error {
+	result, err := r.db.Database.Collection("column_mappings").DeleteOne(ctx, bson.M{
+		"restaurant_id": restaurantID,
+		"name":          name,
+	})
+	if err != nil {
+		return databaseError(err, "failed to delete column mapping profile")
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrColumnMappingProfileNotFound
+	}
+	return nil
+}
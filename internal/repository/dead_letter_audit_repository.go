@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+	"menu-parser/pkg/database"
+)
+
+type DeadLetterAuditRepository struct {
+	db *database.MongoDB
+}
+
+func NewDeadLetterAuditRepository(db *database.MongoDB) repository.DeadLetterAuditRepository {
+	return &DeadLetterAuditRepository{db: db}
+}
+
+func (r *DeadLetterAuditRepository) CreateMany(ctx context.Context, records []entity.DeadLetterAudit) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(records))
+	for i := range records {
+		docs[i] = records[i]
+	}
+
+	_, err := r.db.Database.Collection("dlq_audit").InsertMany(ctx, docs)
+	if err != nil {
+		return databaseError(err, "failed to create dlq audit records")
+	}
+	return nil
+}
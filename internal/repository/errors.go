@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"menu-parser/internal/domain/apperror"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// databaseError wraps a driver error, marking timeouts and network failures
+// as unavailable so callers can retry them
+func databaseError(err error, message string) error {
+	if mongo.IsTimeout(err) || mongo.IsNetworkError(err) || errors.Is(err, context.DeadlineExceeded) {
+		return apperror.Wrap(err, apperror.KindUnavailable, "database_unavailable", message)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+func invalidIDError(err error, message string) error {
+	return apperror.Wrap(err, apperror.KindInvalidArgument, "invalid_id", message)
+}
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"menu-parser/internal/domain/entity"
@@ -12,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MenuRepository struct {
@@ -23,100 +23,360 @@ func NewMenuRepository(db *database.MongoDB) repository.MenuRepository {
 }
 
 func (r *MenuRepository) Create(ctx context.Context, menu *entity.Menu) (*entity.Menu, error) {
+	version, err := r.nextVersion(ctx, menu.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	menu.Version = version
 	menu.CreatedAt = time.Now()
 	menu.UpdatedAt = time.Now()
 
 	result, err := r.db.Database.Collection("menus").InsertOne(ctx, menu)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create menu: %w", err)
+		return nil, databaseError(err, "failed to create menu")
 	}
 
 	menu.ID = result.InsertedID.(primitive.ObjectID)
 	return menu, nil
 }
 
+// Update writes the menu only if its revision is unchanged since it was read,
+// so a product status set in the meantime is not overwritten by the stale copy
+func (r *MenuRepository) Update(ctx context.Context, menu *entity.Menu) error {
+	menu.UpdatedAt = time.Now()
+
+	filter := bson.M{"_id": menu.ID, "revision": menu.Revision}
+	if menu.Revision == 0 {
+		// Menus written before revisions were introduced have no counter yet
+		filter["revision"] = bson.M{"$exists": false}
+	}
+
+	result, err := r.db.Database.Collection("menus").UpdateOne(
+		ctx,
+		filter,
+		bson.M{
+			"$set": bson.M{
+				"name":              menu.Name,
+				"products":          menu.Products,
+				"attributes_groups": menu.AttributesGroups,
+				"attributes":        menu.Attributes,
+				"updated_at":        menu.UpdatedAt,
+			},
+			"$inc": bson.M{"revision": 1},
+		},
+	)
+	if err != nil {
+		return databaseError(err, "failed to update menu")
+	}
+	if result.MatchedCount == 0 {
+		count, err := r.db.Database.Collection("menus").CountDocuments(ctx, bson.M{"_id": menu.ID})
+		if err != nil {
+			return databaseError(err, "failed to update menu")
+		}
+		if count == 0 {
+			return repository.ErrMenuNotFound
+		}
+		return repository.ErrMenuModified
+	}
+
+	menu.Revision++
+	return nil
+}
+
 func (r *MenuRepository) GetByID(ctx context.Context, menuID string) (*entity.Menu, error) {
 	objectID, err := primitive.ObjectIDFromHex(menuID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid menu ID: %w", err)
+		return nil, invalidIDError(err, "invalid menu ID")
 	}
 
 	var menu entity.Menu
 	err = r.db.Database.Collection("menus").FindOne(ctx, bson.M{"_id": objectID}).Decode(&menu)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("menu not found")
+			return nil, repository.ErrMenuNotFound
+		}
+		return nil, databaseError(err, "failed to get menu")
+	}
+
+	return &menu, nil
+}
+
+func (r *MenuRepository) GetActiveByRestaurant(ctx context.Context, restaurantID string) (*entity.Menu, error) {
+	menuID, err := r.activeMenuID(ctx, restaurantID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, databaseError(err, "failed to get active menu")
+	}
+
+	var menu entity.Menu
+	err = r.db.Database.Collection("menus").FindOne(ctx, bson.M{"_id": menuID}).Decode(&menu)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
 		}
-		return nil, fmt.Errorf("failed to get menu: %w", err)
+		return nil, databaseError(err, "failed to get active menu")
 	}
 
 	return &menu, nil
 }
 
+func (r *MenuRepository) ListVersions(ctx context.Context, restaurantID string) ([]entity.MenuVersion, error) {
+	activeID, err := r.activeMenuID(ctx, restaurantID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, databaseError(err, "failed to get active menu")
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"restaurant_id": restaurantID}}},
+		{{Key: "$sort", Value: bson.D{{Key: "version", Value: -1}, {Key: "created_at", Value: -1}}}},
+		{{Key: "$project", Value: bson.M{
+			"version":        1,
+			"created_at":     1,
+			"updated_at":     1,
+			"products_count": bson.M{"$size": bson.M{"$ifNull": bson.A{"$products", bson.A{}}}},
+		}}},
+	}
+
+	cursor, err := r.db.Database.Collection("menus").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, databaseError(err, "failed to list menu versions")
+	}
+	defer cursor.Close(ctx)
+
+	versions := []entity.MenuVersion{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, databaseError(err, "failed to decode menu versions")
+	}
+
+	for i := range versions {
+		versions[i].Active = versions[i].MenuID == activeID
+	}
+
+	return versions, nil
+}
+
+func (r *MenuRepository) Activate(ctx context.Context, restaurantID, menuID string) (*entity.Menu, error) {
+	objectID, err := primitive.ObjectIDFromHex(menuID)
+	if err != nil {
+		return nil, invalidIDError(err, "invalid menu ID")
+	}
+
+	var menu entity.Menu
+	err = r.db.Database.Collection("menus").FindOne(ctx, bson.M{
+		"_id":           objectID,
+		"restaurant_id": restaurantID,
+	}).Decode(&menu)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrMenuNotFound
+		}
+		return nil, databaseError(err, "failed to get menu")
+	}
+
+	_, err = r.db.Database.Collection("active_menus").UpdateOne(
+		ctx,
+		bson.M{"_id": restaurantID},
+		bson.M{"$set": bson.M{
+			"menu_id":    menu.ID,
+			"version":    menu.Version,
+			"updated_at": time.Now(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return nil, databaseError(err, "failed to activate menu")
+	}
+
+	return &menu, nil
+}
+
+// activeMenuID follows the restaurant's active pointer and falls back to the
+// most recently created menu for restaurants that predate versioning
+func (r *MenuRepository) activeMenuID(ctx context.Context, restaurantID string) (primitive.ObjectID, error) {
+	var pointer struct {
+		MenuID primitive.ObjectID `bson:"menu_id"`
+	}
+	err := r.db.Database.Collection("active_menus").FindOne(ctx, bson.M{"_id": restaurantID}).Decode(&pointer)
+	if err == nil {
+		return pointer.MenuID, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return primitive.NilObjectID, err
+	}
+
+	var latest struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err = r.db.Database.Collection("menus").FindOne(
+		ctx,
+		bson.M{"restaurant_id": restaurantID},
+		options.FindOne().
+			SetSort(bson.D{{Key: "created_at", Value: -1}}).
+			SetProjection(bson.M{"_id": 1}),
+	).Decode(&latest)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return latest.ID, nil
+}
+
+// nextVersion allocates the restaurant's next menu version from a counter, so
+// concurrent creations never get the same number. The counter is seeded from
+// the newest stored version, which only matters the first time it is used for
+// restaurants whose menus predate it.
+func (r *MenuRepository) nextVersion(ctx context.Context, restaurantID string) (int, error) {
+	var latest struct {
+		Version int `bson:"version"`
+	}
+	err := r.db.Database.Collection("menus").FindOne(
+		ctx,
+		bson.M{"restaurant_id": restaurantID},
+		options.FindOne().
+			SetSort(bson.D{{Key: "version", Value: -1}}).
+			SetProjection(bson.M{"version": 1}),
+	).Decode(&latest)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return 0, databaseError(err, "failed to get latest menu version")
+	}
+
+	var counter struct {
+		Version int `bson:"version"`
+	}
+	err = r.db.Database.Collection("menu_version_counters").FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": restaurantID},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"version": bson.M{"$add": bson.A{
+					bson.M{"$max": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, latest.Version}},
+					1,
+				}},
+			}}},
+		},
+		options.FindOneAndUpdate().
+			SetUpsert(true).
+			SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, databaseError(err, "failed to allocate menu version")
+	}
+
+	return counter.Version, nil
+}
+
 func (r *MenuRepository) GetProductStatus(ctx context.Context, restaurantID, productID string) (string, error) {
+	menuID, err := r.activeMenuID(ctx, restaurantID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", repository.ErrProductNotFound
+		}
+		return "", databaseError(err, "failed to find product")
+	}
+
 	var menu entity.Menu
 	filter := bson.M{
-		"restaurant_id":   restaurantID,
+		"_id":             menuID,
 		"products.ext_id": productID,
 	}
 
-	err := r.db.Database.Collection("menus").FindOne(ctx, filter).Decode(&menu)
+	err = r.db.Database.Collection("menus").FindOne(
+		ctx,
+		filter,
+		options.FindOne().SetProjection(bson.M{"products.$": 1}),
+	).Decode(&menu)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return "", fmt.Errorf("product not found")
+			return "", repository.ErrProductNotFound
 		}
-		return "", fmt.Errorf("failed to find product: %w", err)
+		return "", databaseError(err, "failed to find product")
 	}
 
-	for _, product := range menu.Products {
-		if product.ExtID == productID {
-			return product.Status, nil
-		}
+	if len(menu.Products) == 0 {
+		return "", repository.ErrProductNotFound
 	}
 
-	return "", fmt.Errorf("product not found in menu")
+	return menu.Products[0].Status, nil
 }
 
-func (r *MenuRepository) UpdateProductStatus(ctx context.Context, restaurantID, productID, newStatus string) (string, error) {
+// UpdateProductStatus sets the status of a single product with a positional
+// update, so concurrent updates of other products in the same menu are not
+// overwritten. The pre-update document is projected down to the matched
+// product to return its previous state in the same round trip. A status set
+// this way is the operator's, so the product no longer counts as missing from
+// the sheet, and the menu revision moves on so a merge in flight retries.
+func (r *MenuRepository) UpdateProductStatus(ctx context.Context, restaurantID, productID, newStatus string) (primitive.ObjectID, *entity.Product, error) {
+	menuID, err := r.activeMenuID(ctx, restaurantID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return primitive.NilObjectID, nil, repository.ErrProductNotFound
+		}
+		return primitive.NilObjectID, nil, databaseError(err, "failed to find product")
+	}
+
 	var menu entity.Menu
 	filter := bson.M{
-		"restaurant_id":   restaurantID,
+		"_id":             menuID,
 		"products.ext_id": productID,
 	}
+	update := bson.M{
+		"$set": bson.M{
+			"products.$.status": newStatus,
+			"updated_at":        time.Now(),
+		},
+		"$unset": bson.M{"products.$.missing_from_sheet": ""},
+		"$inc":   bson.M{"revision": 1},
+	}
 
-	err := r.db.Database.Collection("menus").FindOne(ctx, filter).Decode(&menu)
+	err = r.db.Database.Collection("menus").FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().
+			SetProjection(bson.M{"products.$": 1}).
+			SetReturnDocument(options.Before),
+	).Decode(&menu)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return "", fmt.Errorf("product not found")
+			return primitive.NilObjectID, nil, repository.ErrProductNotFound
 		}
-		return "", fmt.Errorf("failed to find product: %w", err)
+		return primitive.NilObjectID, nil, databaseError(err, "failed to update product status")
 	}
 
-	var oldStatus string
-	for i, product := range menu.Products {
-		if product.ExtID == productID {
-			oldStatus = product.Status
-			menu.Products[i].Status = newStatus
-			menu.UpdatedAt = time.Now()
-			break
-		}
+	if len(menu.Products) == 0 {
+		return primitive.NilObjectID, nil, repository.ErrProductNotFound
 	}
 
-	if oldStatus == "" {
-		return "", fmt.Errorf("product not found in menu")
-	}
+	return menu.ID, &menu.Products[0], nil
+}
 
-	_, err = r.db.Database.Collection("menus").UpdateOne(
+func (r *MenuRepository) FindByProduct(ctx context.Context, productID string) ([]entity.Menu, error) {
+	cursor, err := r.db.Database.Collection("menus").Find(
 		ctx,
-		bson.M{"_id": menu.ID},
-		bson.M{"$set": bson.M{
-			"products":   menu.Products,
-			"updated_at": menu.UpdatedAt,
-		}},
+		bson.M{"products.ext_id": productID},
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: 1}}).
+			SetProjection(bson.M{
+				"restaurant_id": 1,
+				"name":          1,
+				"version":       1,
+				"created_at":    1,
+				"updated_at":    1,
+				"products.$":    1,
+			}),
 	)
 	if err != nil {
-		return "", fmt.Errorf("failed to update product status: %w", err)
+		return nil, databaseError(err, "failed to find menus by product")
+	}
+	defer cursor.Close(ctx)
+
+	menus := []entity.Menu{}
+	if err := cursor.All(ctx, &menus); err != nil {
+		return nil, databaseError(err, "failed to decode menus")
 	}
 
-	return oldStatus, nil
+	return menus, nil
 }
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+	"menu-parser/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OutboxRepository struct {
+	db *database.MongoDB
+}
+
+func NewOutboxRepository(db *database.MongoDB) repository.OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+func (r *OutboxRepository) Create(ctx context.Context, message *entity.OutboxMessage) error {
+	result, err := r.db.Database.Collection("outbox").InsertOne(ctx, message)
+	if err != nil {
+		return databaseError(err, "failed to create outbox message")
+	}
+	message.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// ClaimNext leases the oldest due message with FindOneAndUpdate, so concurrent
+// relays in the API and the worker never lease the same message
+func (r *OutboxRepository) ClaimNext(ctx context.Context, lease time.Duration) (*entity.OutboxMessage, error) {
+	now := time.Now()
+
+	var message entity.OutboxMessage
+	err := r.db.Database.Collection("outbox").FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"status":       entity.OutboxStatusPending,
+			"available_at": bson.M{"$lte": now},
+		},
+		bson.M{
+			"$set": bson.M{
+				"available_at": now.Add(lease),
+				"updated_at":   now,
+			},
+			"$inc": bson.M{"attempts": 1},
+		},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&message)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, databaseError(err, "failed to claim outbox message")
+	}
+
+	return &message, nil
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now()
+	_, err := r.db.Database.Collection("outbox").UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set": bson.M{
+				"status":       entity.OutboxStatusPublished,
+				"published_at": now,
+				"updated_at":   now,
+			},
+			"$unset": bson.M{"last_error": ""},
+		},
+	)
+	if err != nil {
+		return databaseError(err, "failed to mark outbox message published")
+	}
+	return nil
+}
+
+func (r *OutboxRepository) ScheduleRetry(ctx context.Context, id primitive.ObjectID, lastError string, availableAt time.Time) error {
+	_, err := r.db.Database.Collection("outbox").UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{
+			"last_error":   lastError,
+			"available_at": availableAt,
+			"updated_at":   time.Now(),
+		}},
+	)
+	if err != nil {
+		return databaseError(err, "failed to schedule outbox retry")
+	}
+	return nil
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id primitive.ObjectID, lastError string) error {
+	_, err := r.db.Database.Collection("outbox").UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{
+			"status":     entity.OutboxStatusFailed,
+			"last_error": lastError,
+			"updated_at": time.Now(),
+		}},
+	)
+	if err != nil {
+		return databaseError(err, "failed to mark outbox message failed")
+	}
+	return nil
+}
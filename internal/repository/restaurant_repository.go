@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+	"menu-parser/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RestaurantRepository struct {
+	db *database.MongoDB
+}
+
+func NewRestaurantRepository(db *database.MongoDB) repository.RestaurantRepository {
+	return &RestaurantRepository{db: db}
+}
+
+func (r *RestaurantRepository) Create(ctx context.Context, restaurant *entity.Restaurant) error {
+	restaurant.NameKey = entity.RestaurantNameKey(restaurant.Name)
+	restaurant.CreatedAt = time.Now()
+	restaurant.UpdatedAt = time.Now()
+
+	_, err := r.db.Database.Collection("restaurants").InsertOne(ctx, restaurant)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return repository.ErrRestaurantExists
+		}
+		return databaseError(err, "failed to create restaurant")
+	}
+	return nil
+}
+
+func (r *RestaurantRepository) GetByID(ctx context.Context, restaurantID string) (*entity.Restaurant, error) {
+	var restaurant entity.Restaurant
+	err := r.db.Database.Collection("restaurants").FindOne(ctx, bson.M{"_id": restaurantID}).Decode(&restaurant)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrRestaurantNotFound
+		}
+		return nil, databaseError(err, "failed to get restaurant")
+	}
+	return &restaurant, nil
+}
+
+func (r *RestaurantRepository) List(ctx context.Context) ([]entity.Restaurant, error) {
+	cursor, err := r.db.Database.Collection("restaurants").Find(
+		ctx,
+		bson.M{},
+		options.Find().SetSort(bson.D{{Key: "name_key", Value: 1}}),
+	)
+	if err != nil {
+		return nil, databaseError(err, "failed to list restaurants")
+	}
+	defer cursor.Close(ctx)
+
+	restaurants := []entity.Restaurant{}
+	if err := cursor.All(ctx, &restaurants); err != nil {
+		return nil, databaseError(err, "failed to decode restaurants")
+	}
+	return restaurants, nil
+}
+
+func (r *RestaurantRepository) Update(ctx context.Context, restaurant *entity.Restaurant) error {
+	restaurant.NameKey = entity.RestaurantNameKey(restaurant.Name)
+	restaurant.UpdatedAt = time.Now()
+
+	result, err := r.db.Database.Collection("restaurants").UpdateOne(
+		ctx,
+		bson.M{"_id": restaurant.ID},
+		bson.M{"$set": bson.M{
+			"name":           restaurant.Name,
+			"name_key":       restaurant.NameKey,
+			"spreadsheet_id": restaurant.SpreadsheetID,
+			"timezone":       restaurant.Timezone,
+			"settings":       restaurant.Settings,
+			"updated_at":     restaurant.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return repository.ErrRestaurantExists
+		}
+		return databaseError(err, "failed to update restaurant")
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrRestaurantNotFound
+	}
+	return nil
+}
+
+func (r *RestaurantRepository) Delete(ctx context.Context, restaurantID string) error {
+	result, err := r.db.Database.Collection("restaurants").DeleteOne(ctx, bson.M{"_id": restaurantID})
+	if err != nil {
+		return databaseError(err, "failed to delete restaurant")
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrRestaurantNotFound
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type TaskRepository struct {
@@ -25,7 +28,7 @@ func NewTaskRepository(db *database.MongoDB) repository.TaskRepository {
 func (r *TaskRepository) Create(ctx context.Context, task *entity.ParsingTask) error {
 	_, err := r.db.Database.Collection("parsing_tasks").InsertOne(ctx, task)
 	if err != nil {
-		return fmt.Errorf("failed to create parsing task: %w", err)
+		return databaseError(err, "failed to create parsing task")
 	}
 	return nil
 }
@@ -35,14 +38,97 @@ func (r *TaskRepository) GetByID(ctx context.Context, taskID string) (*entity.Pa
 	err := r.db.Database.Collection("parsing_tasks").FindOne(ctx, bson.M{"_id": taskID}).Decode(&task)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("task not found")
+			return nil, repository.ErrTaskNotFound
 		}
-		return nil, fmt.Errorf("failed to get parsing task: %w", err)
+		return nil, databaseError(err, "failed to get parsing task")
 	}
 	return &task, nil
 }
 
-func (r *TaskRepository) UpdateStatus(ctx context.Context, taskID string, status entity.ParsingTaskStatus, menuID *primitive.ObjectID, errorMsg string) error {
+func (r *TaskRepository) List(ctx context.Context, filter repository.TaskFilter) ([]entity.ParsingTask, string, error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = repository.TaskSortCreatedAt
+	}
+	order := -1
+	if filter.Ascending {
+		order = 1
+	}
+
+	conditions := bson.A{}
+	if filter.Status != "" {
+		conditions = append(conditions, bson.M{"status": filter.Status})
+	}
+	if filter.SpreadsheetID != "" {
+		conditions = append(conditions, bson.M{"spreadsheet_id": filter.SpreadsheetID})
+	}
+	if filter.RestaurantKeys != nil {
+		// Tasks without a registered restaurant are keyed by the restaurant name
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"restaurant_id": bson.M{"$in": filter.RestaurantKeys}},
+			bson.M{"restaurant_id": bson.M{"$exists": false}, "restaurant_name": bson.M{"$in": filter.RestaurantKeys}},
+		}})
+	}
+
+	createdAt := bson.M{}
+	if !filter.CreatedFrom.IsZero() {
+		createdAt["$gte"] = filter.CreatedFrom
+	}
+	if !filter.CreatedTo.IsZero() {
+		createdAt["$lte"] = filter.CreatedTo
+	}
+	if len(createdAt) > 0 {
+		conditions = append(conditions, bson.M{"created_at": createdAt})
+	}
+
+	if filter.Cursor != "" {
+		after, err := decodeTaskCursor(filter.Cursor, sortBy)
+		if err != nil {
+			return nil, "", invalidIDError(err, "invalid cursor")
+		}
+		// Tasks created in the same millisecond are ordered by ID
+		op := "$lt"
+		if filter.Ascending {
+			op = "$gt"
+		}
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{string(sortBy): bson.M{op: after.At}},
+			bson.M{string(sortBy): after.At, "_id": bson.M{op: after.ID}},
+		}})
+	}
+
+	query := bson.M{}
+	if len(conditions) > 0 {
+		query["$and"] = conditions
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: string(sortBy), Value: order}, {Key: "_id", Value: order}})
+	if filter.Limit > 0 {
+		// One extra task tells whether another page exists
+		findOptions.SetLimit(int64(filter.Limit + 1))
+	}
+
+	cursor, err := r.db.Database.Collection("parsing_tasks").Find(ctx, query, findOptions)
+	if err != nil {
+		return nil, "", databaseError(err, "failed to list parsing tasks")
+	}
+	defer cursor.Close(ctx)
+
+	tasks := []entity.ParsingTask{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, "", databaseError(err, "failed to decode parsing tasks")
+	}
+
+	nextCursor := ""
+	if filter.Limit > 0 && len(tasks) > filter.Limit {
+		tasks = tasks[:filter.Limit]
+		nextCursor = encodeTaskCursor(&tasks[len(tasks)-1], sortBy)
+	}
+
+	return tasks, nextCursor, nil
+}
+
+func (r *TaskRepository) UpdateStatus(ctx context.Context, taskID string, status entity.ParsingTaskStatus, menuID *primitive.ObjectID, errorMsg string) (bool, error) {
 	update := bson.M{
 		"$set": bson.M{
 			"status":     string(status),
@@ -58,13 +144,61 @@ func (r *TaskRepository) UpdateStatus(ctx context.Context, taskID string, status
 		update["$set"].(bson.M)["error_message"] = errorMsg
 	}
 
+	result, err := r.db.Database.Collection("parsing_tasks").UpdateOne(
+		ctx,
+		notCancelled(taskID),
+		update,
+	)
+	if err != nil {
+		return false, databaseError(err, "failed to update parsing task")
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *TaskRepository) MarkFailed(ctx context.Context, taskID, errorCode, errorMsg string) (bool, error) {
+	result, err := r.db.Database.Collection("parsing_tasks").UpdateOne(
+		ctx,
+		notCancelled(taskID),
+		bson.M{"$set": bson.M{
+			"status":        entity.TaskStatusFailed,
+			"error_code":    errorCode,
+			"error_message": errorMsg,
+			"updated_at":    time.Now(),
+		}},
+	)
+	if err != nil {
+		return false, databaseError(err, "failed to update parsing task")
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *TaskRepository) RecordError(ctx context.Context, taskID, errorCode, errorMsg string) error {
+	_, err := r.db.Database.Collection("parsing_tasks").UpdateOne(
+		ctx,
+		notCancelled(taskID),
+		bson.M{"$set": bson.M{
+			"error_code":    errorCode,
+			"error_message": errorMsg,
+			"updated_at":    time.Now(),
+		}},
+	)
+	if err != nil {
+		return databaseError(err, "failed to update parsing task")
+	}
+	return nil
+}
+
+func (r *TaskRepository) SaveDiff(ctx context.Context, taskID string, diff *entity.MenuDiff) error {
 	_, err := r.db.Database.Collection("parsing_tasks").UpdateOne(
 		ctx,
 		bson.M{"_id": taskID},
-		update,
+		bson.M{"$set": bson.M{
+			"diff":       diff,
+			"updated_at": time.Now(),
+		}},
 	)
 	if err != nil {
-		return fmt.Errorf("failed to update parsing task: %w", err)
+		return databaseError(err, "failed to save menu diff")
 	}
 	return nil
 }
@@ -81,4 +215,198 @@ func (r *TaskRepository) IncrementRetryCount(ctx context.Context, taskID string)
 	return err
 }
 
+func (r *TaskRepository) ClaimForProcessing(ctx context.Context, taskID string) (bool, error) {
+	result, err := r.db.Database.Collection("parsing_tasks").UpdateOne(
+		ctx,
+		bson.M{"_id": taskID, "status": entity.TaskStatusQueued},
+		bson.M{"$set": bson.M{
+			"status":     entity.TaskStatusProcessing,
+			"updated_at": time.Now(),
+		}},
+	)
+	if err != nil {
+		return false, databaseError(err, "failed to claim parsing task")
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *TaskRepository) FindStale(ctx context.Context, updatedBefore time.Time, limit int) ([]entity.ParsingTask, error) {
+	cursor, err := r.db.Database.Collection("parsing_tasks").Find(
+		ctx,
+		bson.M{
+			"status":     bson.M{"$in": bson.A{entity.TaskStatusQueued, entity.TaskStatusProcessing}},
+			"updated_at": bson.M{"$lt": updatedBefore},
+		},
+		options.Find().
+			SetSort(bson.D{{Key: "updated_at", Value: 1}}).
+			SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, databaseError(err, "failed to find stale parsing tasks")
+	}
+	defer cursor.Close(ctx)
 
+	tasks := []entity.ParsingTask{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, databaseError(err, "failed to decode parsing tasks")
+	}
+	return tasks, nil
+}
+
+func (r *TaskRepository) RequeueStale(ctx context.Context, task *entity.ParsingTask) (bool, error) {
+	result, err := r.db.Database.Collection("parsing_tasks").UpdateOne(
+		ctx,
+		staleTaskFilter(task),
+		bson.M{
+			"$set": bson.M{
+				"status":     entity.TaskStatusQueued,
+				"updated_at": time.Now(),
+			},
+			"$inc": bson.M{"retry_count": 1},
+		},
+	)
+	if err != nil {
+		return false, databaseError(err, "failed to requeue parsing task")
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *TaskRepository) FailStale(ctx context.Context, task *entity.ParsingTask, errorCode, reason string) (bool, error) {
+	result, err := r.db.Database.Collection("parsing_tasks").UpdateOne(
+		ctx,
+		staleTaskFilter(task),
+		bson.M{"$set": bson.M{
+			"status":        entity.TaskStatusFailed,
+			"error_code":    errorCode,
+			"error_message": reason,
+			"updated_at":    time.Now(),
+		}},
+	)
+	if err != nil {
+		return false, databaseError(err, "failed to fail parsing task")
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *TaskRepository) RequeueFailed(ctx context.Context, taskID, requestedBy string) (bool, error) {
+	return r.requeue(ctx, taskID, requestedBy, entity.TaskStatusFailed)
+}
+
+func (r *TaskRepository) RequeueFailedOrCancelled(ctx context.Context, taskID, requestedBy string) (bool, error) {
+	return r.requeue(ctx, taskID, requestedBy, entity.TaskStatusFailed, entity.TaskStatusCancelled)
+}
+
+// requeue moves a task in one of the given statuses back to queued with a
+// fresh retry budget, clearing its error and cancellation
+func (r *TaskRepository) requeue(ctx context.Context, taskID, requestedBy string, from ...entity.ParsingTaskStatus) (bool, error) {
+	now := time.Now()
+	result, err := r.db.Database.Collection("parsing_tasks").UpdateOne(
+		ctx,
+		bson.M{
+			"_id":    taskID,
+			"status": bson.M{"$in": from},
+		},
+		bson.M{
+			"$set": bson.M{
+				"status":      entity.TaskStatusQueued,
+				"retry_count": 0,
+				"retried_by":  requestedBy,
+				"retried_at":  now,
+				"updated_at":  now,
+			},
+			"$unset": bson.M{
+				"error_code":    "",
+				"error_message": "",
+				"cancelled_by":  "",
+				"cancelled_at":  "",
+			},
+		},
+	)
+	if err != nil {
+		return false, databaseError(err, "failed to requeue parsing task")
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *TaskRepository) RecordWebhookDelivery(ctx context.Context, taskID string, delivery *entity.WebhookDelivery) error {
+	_, err := r.db.Database.Collection("parsing_tasks").UpdateOne(
+		ctx,
+		bson.M{"_id": taskID},
+		bson.M{"$push": bson.M{"webhook_deliveries": delivery}},
+	)
+	if err != nil {
+		return databaseError(err, "failed to record webhook delivery")
+	}
+	return nil
+}
+
+func (r *TaskRepository) Cancel(ctx context.Context, taskID, requestedBy string) (bool, error) {
+	now := time.Now()
+	result, err := r.db.Database.Collection("parsing_tasks").UpdateOne(
+		ctx,
+		bson.M{
+			"_id":    taskID,
+			"status": bson.M{"$in": bson.A{entity.TaskStatusQueued, entity.TaskStatusProcessing}},
+		},
+		bson.M{"$set": bson.M{
+			"status":       entity.TaskStatusCancelled,
+			"cancelled_by": requestedBy,
+			"cancelled_at": now,
+			"updated_at":   now,
+		}},
+	)
+	if err != nil {
+		return false, databaseError(err, "failed to cancel parsing task")
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// notCancelled matches the task unless it was cancelled, so a worker still
+// processing it cannot overwrite the cancellation
+func notCancelled(taskID string) bson.M {
+	return bson.M{
+		"_id":    taskID,
+		"status": bson.M{"$ne": entity.TaskStatusCancelled},
+	}
+}
+
+// taskCursor is the position of the last task of a page in the listing order
+type taskCursor struct {
+	SortBy repository.TaskSortField `json:"s"`
+	At     time.Time                `json:"t"`
+	ID     string                   `json:"id"`
+}
+
+func encodeTaskCursor(task *entity.ParsingTask, sortBy repository.TaskSortField) string {
+	at := task.CreatedAt
+	if sortBy == repository.TaskSortUpdatedAt {
+		at = task.UpdatedAt
+	}
+	data, _ := json.Marshal(taskCursor{SortBy: sortBy, At: at, ID: task.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeTaskCursor rejects malformed cursors and cursors of a listing in another order
+func decodeTaskCursor(value string, sortBy repository.TaskSortField) (*taskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor taskCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.SortBy != sortBy || cursor.ID == "" {
+		return nil, fmt.Errorf("cursor does not belong to a listing sorted by %s", sortBy)
+	}
+	return &cursor, nil
+}
+
+// staleTaskFilter matches the task only while it is unchanged since it was read
+func staleTaskFilter(task *entity.ParsingTask) bson.M {
+	return bson.M{
+		"_id":        task.ID,
+		"status":     task.Status,
+		"updated_at": task.UpdatedAt,
+	}
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+)
+
+func TestTaskCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 12, 0, 0, 123000000, time.UTC)
+	task := &entity.ParsingTask{ID: "task-1", CreatedAt: createdAt, UpdatedAt: createdAt.Add(time.Hour)}
+
+	for _, sortBy := range []repository.TaskSortField{repository.TaskSortCreatedAt, repository.TaskSortUpdatedAt} {
+		t.Run(string(sortBy), func(t *testing.T) {
+			cursor, err := decodeTaskCursor(encodeTaskCursor(task, sortBy), sortBy)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			want := task.CreatedAt
+			if sortBy == repository.TaskSortUpdatedAt {
+				want = task.UpdatedAt
+			}
+			if cursor.ID != task.ID || cursor.SortBy != sortBy || !cursor.At.Equal(want) {
+				t.Errorf("cursor = %+v, want %s at %s", cursor, task.ID, want)
+			}
+		})
+	}
+}
+
+func TestDecodeTaskCursorRejectsMalformed(t *testing.T) {
+	task := &entity.ParsingTask{ID: "task-1", CreatedAt: time.Now()}
+	encode := func(json string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(json))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"s":"created_at","t":"2026-03-01T12:00:00Z","id":"task-1"}`))},
+		{"not JSON", encode("task-1")},
+		{"time not RFC 3339", encode(`{"s":"created_at","t":"yesterday","id":"task-1"}`)},
+		{"without ID", encode(`{"s":"created_at","t":"2026-03-01T12:00:00Z"}`)},
+		{"without sort field", encode(`{"t":"2026-03-01T12:00:00Z","id":"task-1"}`)},
+		{"other sort field", encodeTaskCursor(task, repository.TaskSortUpdatedAt)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cursor, err := decodeTaskCursor(tt.cursor, repository.TaskSortCreatedAt); err == nil {
+				t.Errorf("decodeTaskCursor(%q) = %+v, want an error", tt.cursor, cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"menu-parser/internal/domain/repository"
+	"menu-parser/pkg/database"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type Transactor struct {
+	db *database.MongoDB
+}
+
+func NewTransactor(db *database.MongoDB) repository.Transactor {
+	return &Transactor{db: db}
+}
+
+// WithinTransaction runs fn in a multi-document transaction. A standalone
+// MongoDB server has no transactions, so there fn runs without one and a
+// failure between its writes is not rolled back; the services only start on
+// such a server when MONGODB_ALLOW_NO_TRANSACTIONS is set.
+func (t *Transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !t.db.SupportsTransactions {
+		return fn(ctx)
+	}
+
+	session, err := t.db.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
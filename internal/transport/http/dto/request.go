@@ -1,11 +1,145 @@
 package dto
 
+import (
+	"strings"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+)
+
 type ParseRequest struct {
-	SpreadsheetID  string `json:"spreadsheet_id" binding:"required"`
-	RestaurantName string `json:"restaurant_name" binding:"required"`
+	RestaurantID   string                `json:"restaurant_id"`
+	SpreadsheetID  string                `json:"spreadsheet_id"`
+	RestaurantName string                `json:"restaurant_name"`
+	ColumnMapping  *ColumnMappingRequest `json:"column_mapping"`
+	MappingProfile string                `json:"mapping_profile"`
+	Sheets         []string              `json:"sheets"`
+	AllSheets      bool                  `json:"all_sheets"`
+	Mode           string                `json:"mode" binding:"omitempty,oneof=create merge"`
+	// Activate defaults to true; false stores the parsed menu as an inactive version
+	Activate *bool `json:"activate"`
+	// CallbackURL receives a signed POST once the task completed or failed for good
+	CallbackURL string `json:"callback_url"`
 }
 
 type ProductStatusUpdateRequest struct {
 	Status string `json:"status" binding:"required,oneof=available not_available deleted"`
 	Reason string `json:"reason"`
 }
+
+// AuditQuery filters the product status history; from and to are RFC 3339 timestamps
+type AuditQuery struct {
+	UserID    string    `form:"user_id"`
+	Status    string    `form:"status" binding:"omitempty,oneof=available not_available deleted"`
+	EventType string    `form:"event_type"`
+	From      time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To        time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor    string    `form:"cursor"`
+	Limit     int       `form:"limit" binding:"min=0"`
+}
+
+func (q *AuditQuery) ToFilter(restaurantID, productID string) repository.AuditFilter {
+	return repository.AuditFilter{
+		RestaurantID: restaurantID,
+		ProductID:    productID,
+		UserID:       q.UserID,
+		Status:       q.Status,
+		EventType:    entity.ProductEventType(q.EventType),
+		From:         q.From,
+		To:           q.To,
+		Cursor:       q.Cursor,
+		Limit:        q.Limit,
+	}
+}
+
+// TaskQuery filters parsing tasks; from and to bound the creation time and are
+// RFC 3339 timestamps. sort names a timestamp, with a "-" prefix for newest first.
+type TaskQuery struct {
+	Status        string    `form:"status" binding:"omitempty,oneof=queued processing completed failed cancelled"`
+	RestaurantID  string    `form:"restaurant_id"`
+	SpreadsheetID string    `form:"spreadsheet_id"`
+	From          time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To            time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Sort          string    `form:"sort" binding:"omitempty,oneof=created_at -created_at updated_at -updated_at"`
+	Cursor        string    `form:"cursor"`
+	Limit         int       `form:"limit" binding:"min=0"`
+}
+
+func (q *TaskQuery) ToFilter() repository.TaskFilter {
+	filter := repository.TaskFilter{
+		Status:        entity.ParsingTaskStatus(q.Status),
+		SpreadsheetID: q.SpreadsheetID,
+		CreatedFrom:   q.From,
+		CreatedTo:     q.To,
+		Cursor:        q.Cursor,
+		Limit:         q.Limit,
+	}
+	if q.RestaurantID != "" {
+		filter.RestaurantKeys = []string{q.RestaurantID}
+	}
+	if q.Sort != "" {
+		sortBy, descending := strings.CutPrefix(q.Sort, "-")
+		filter.SortBy = repository.TaskSortField(sortBy)
+		filter.Ascending = !descending
+	}
+	return filter
+}
+
+// DeadLetterRequest selects dead-lettered messages by ID, or every message
+// among the first limit ones with all set
+type DeadLetterRequest struct {
+	MessageIDs []string `json:"message_ids"`
+	All        bool     `json:"all"`
+	Limit      int      `json:"limit" binding:"min=0"`
+}
+
+// ColumnMappingRequest references columns by header name or column letter
+type ColumnMappingRequest struct {
+	HeaderRow   int    `json:"header_row" binding:"min=0"`
+	ExtID       string `json:"ext_id"`
+	ProductName string `json:"product_name" binding:"required"`
+	Price       string `json:"price"`
+	PriceOld    string `json:"price_old"`
+	Options     string `json:"options"`
+}
+
+func (r *ColumnMappingRequest) ToEntity() *entity.ColumnMapping {
+	return &entity.ColumnMapping{
+		HeaderRow:   r.HeaderRow,
+		ExtID:       r.ExtID,
+		ProductName: r.ProductName,
+		Price:       r.Price,
+		PriceOld:    r.PriceOld,
+		Options:     r.Options,
+	}
+}
+
+type RestaurantRequest struct {
+	Name          string                    `json:"name" binding:"required"`
+	SpreadsheetID string                    `json:"spreadsheet_id"`
+	Timezone      string                    `json:"timezone"`
+	Settings      RestaurantSettingsRequest `json:"settings"`
+}
+
+type RestaurantSettingsRequest struct {
+	MappingProfile string   `json:"mapping_profile"`
+	Sheets         []string `json:"sheets"`
+	AllSheets      bool     `json:"all_sheets"`
+	ParseMode      string   `json:"parse_mode" binding:"omitempty,oneof=create merge"`
+}
+
+func (r *RestaurantRequest) ToEntity(restaurantID string) *entity.Restaurant {
+	return &entity.Restaurant{
+		ID:            restaurantID,
+		Name:          r.Name,
+		SpreadsheetID: r.SpreadsheetID,
+		Timezone:      r.Timezone,
+		Settings: entity.RestaurantSettings{
+			MappingProfile: r.Settings.MappingProfile,
+			Sheets:         r.Settings.Sheets,
+			AllSheets:      r.Settings.AllSheets,
+			ParseMode:      entity.ParseMode(r.Settings.ParseMode),
+		},
+	}
+}
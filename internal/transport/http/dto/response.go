@@ -12,12 +12,35 @@ type ParseResponse struct {
 }
 
 type TaskStatusResponse struct {
-	TaskID    string    `json:"task_id"`
-	Status    string    `json:"status"`
-	MenuID    string    `json:"menu_id,omitempty"`
-	Error     string    `json:"error,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	TaskID            string                   `json:"task_id"`
+	Status            string                   `json:"status"`
+	RestaurantID      string                   `json:"restaurant_id,omitempty"`
+	RestaurantName    string                   `json:"restaurant_name,omitempty"`
+	SpreadsheetID     string                   `json:"spreadsheet_id,omitempty"`
+	MenuID            string                   `json:"menu_id,omitempty"`
+	Error             string                   `json:"error,omitempty"`
+	ErrorCode         string                   `json:"error_code,omitempty"`
+	DiffSummary       *entity.MenuDiffSummary  `json:"diff_summary,omitempty"`
+	RetriedBy         string                   `json:"retried_by,omitempty"`
+	CancelledBy       string                   `json:"cancelled_by,omitempty"`
+	CallbackURL       string                   `json:"callback_url,omitempty"`
+	WebhookDeliveries []entity.WebhookDelivery `json:"webhook_deliveries,omitempty"`
+	CreatedAt         time.Time                `json:"created_at"`
+	UpdatedAt         time.Time                `json:"updated_at"`
+}
+
+type TaskListResponse struct {
+	Items      []*TaskStatusResponse `json:"items"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+type AuditListResponse struct {
+	Items      []entity.ProductStatusAudit `json:"items"`
+	NextCursor string                      `json:"next_cursor,omitempty"`
+}
+
+type DeadLetterListResponse struct {
+	Items []entity.DeadLetter `json:"items"`
 }
 
 type ProductStatusUpdateResponse struct {
@@ -29,6 +52,7 @@ type MenuResponse struct {
 	ID               string                   `json:"_id"`
 	Name             string                   `json:"name"`
 	RestaurantID     string                   `json:"restaurant_id"`
+	Version          int                      `json:"version"`
 	Products         []entity.Product         `json:"products"`
 	AttributesGroups []entity.AttributesGroup `json:"attributes_groups"`
 	Attributes       []entity.Attribute       `json:"attributes"`
@@ -41,6 +65,7 @@ func ToMenuResponse(menu *entity.Menu) *MenuResponse {
 		ID:               menu.ID.Hex(),
 		Name:             menu.Name,
 		RestaurantID:     menu.RestaurantID,
+		Version:          menu.Version,
 		Products:         menu.Products,
 		AttributesGroups: menu.AttributesGroups,
 		Attributes:       menu.Attributes,
@@ -51,10 +76,17 @@ func ToMenuResponse(menu *entity.Menu) *MenuResponse {
 
 func ToTaskStatusResponse(task *entity.ParsingTask) *TaskStatusResponse {
 	resp := &TaskStatusResponse{
-		TaskID:    task.ID,
-		Status:    string(task.Status),
-		CreatedAt: task.CreatedAt,
-		UpdatedAt: task.UpdatedAt,
+		TaskID:            task.ID,
+		Status:            string(task.Status),
+		RestaurantID:      task.RestaurantID,
+		RestaurantName:    task.RestaurantName,
+		SpreadsheetID:     task.SpreadsheetID,
+		RetriedBy:         task.RetriedBy,
+		CancelledBy:       task.CancelledBy,
+		CallbackURL:       task.CallbackURL,
+		WebhookDeliveries: task.WebhookDeliveries,
+		CreatedAt:         task.CreatedAt,
+		UpdatedAt:         task.UpdatedAt,
 	}
 
 	if task.MenuID != nil {
@@ -63,7 +95,24 @@ func ToTaskStatusResponse(task *entity.ParsingTask) *TaskStatusResponse {
 
 	if task.ErrorMessage != "" {
 		resp.Error = task.ErrorMessage
+		resp.ErrorCode = task.ErrorCode
+	}
+
+	if task.Diff != nil {
+		summary := task.Diff.Summary()
+		resp.DiffSummary = &summary
 	}
 
 	return resp
 }
+
+// ErrorResponse is the envelope of every API error
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id"`
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"menu-parser/internal/transport/http/dto"
+	"menu-parser/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditUseCase *usecase.AuditUseCase
+}
+
+func NewAuditHandler(auditUseCase *usecase.AuditUseCase) *AuditHandler {
+	return &AuditHandler{
+		auditUseCase: auditUseCase,
+	}
+}
+
+func (h *AuditHandler) ListProductAudit(c *gin.Context) {
+	h.listAudit(c, c.Param("product_id"))
+}
+
+func (h *AuditHandler) ListRestaurantAudit(c *gin.Context) {
+	h.listAudit(c, "")
+}
+
+func (h *AuditHandler) listAudit(c *gin.Context, productID string) {
+	var query dto.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	audits, nextCursor, err := h.auditUseCase.ListAudit(c.Request.Context(), query.ToFilter(c.Param("restaurant_id"), productID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.AuditListResponse{
+		Items:      audits,
+		NextCursor: nextCursor,
+	})
+}
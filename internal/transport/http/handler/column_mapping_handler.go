@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+
+	"menu-parser/internal/transport/http/dto"
+	"menu-parser/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ColumnMappingHandler struct {
+	mappingUseCase *usecase.ColumnMappingUseCase
+}
+
+func NewColumnMappingHandler(mappingUseCase *usecase.ColumnMappingUseCase) *ColumnMappingHandler {
+	return &ColumnMappingHandler{
+		mappingUseCase: mappingUseCase,
+	}
+}
+
+func (h *ColumnMappingHandler) SaveProfile(c *gin.Context) {
+	restaurantID := c.Param("restaurant_id")
+	name := c.Param("name")
+
+	var req dto.ColumnMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	mapping := req.ToEntity()
+	if err := mapping.Validate(); err != nil {
+		c.Error(err)
+		return
+	}
+
+	profile, err := h.mappingUseCase.SaveProfile(c.Request.Context(), restaurantID, name, *mapping)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *ColumnMappingHandler) GetProfile(c *gin.Context) {
+	profile, err := h.mappingUseCase.GetProfile(c.Request.Context(), c.Param("restaurant_id"), c.Param("name"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *ColumnMappingHandler) ListProfiles(c *gin.Context) {
+	profiles, err := h.mappingUseCase.ListProfiles(c.Request.Context(), c.Param("restaurant_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profiles)
+}
+
+func (h *ColumnMappingHandler) DeleteProfile(c *gin.Context) {
+	if err := h.mappingUseCase.DeleteProfile(c.Request.Context(), c.Param("restaurant_id"), c.Param("name")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
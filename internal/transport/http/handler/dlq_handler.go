@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/transport/http/dto"
+	"menu-parser/internal/transport/http/middleware"
+	"menu-parser/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DLQHandler struct {
+	dlqUseCase *usecase.DLQUseCase
+}
+
+func NewDLQHandler(dlqUseCase *usecase.DLQUseCase) *DLQHandler {
+	return &DLQHandler{
+		dlqUseCase: dlqUseCase,
+	}
+}
+
+func (h *DLQHandler) ListDeadLetters(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		c.Error(fmt.Errorf("%w: limit must be a non-negative integer", usecase.ErrInvalidDeadLetterRequest))
+		return
+	}
+
+	letters, err := h.dlqUseCase.List(c.Request.Context(), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.DeadLetterListResponse{Items: letters})
+}
+
+func (h *DLQHandler) ReplayDeadLetters(c *gin.Context) {
+	h.drain(c, h.dlqUseCase.Replay)
+}
+
+func (h *DLQHandler) PurgeDeadLetters(c *gin.Context) {
+	h.drain(c, h.dlqUseCase.Purge)
+}
+
+type drainFunc func(ctx context.Context, messageIDs []string, all bool, limit int, userID string) ([]entity.DeadLetter, error)
+
+func (h *DLQHandler) drain(c *gin.Context, fn drainFunc) {
+	var req dto.DeadLetterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	userID := c.GetString(middleware.UserIDKey)
+	if userID == "" {
+		userID = "system"
+	}
+
+	letters, err := fn(c.Request.Context(), req.MessageIDs, req.All, req.Limit, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.DeadLetterListResponse{Items: letters})
+}
@@ -0,0 +1,8 @@
+package handler
+
+import "menu-parser/internal/domain/apperror"
+
+// invalidRequest marks a body or query that could not be bound as a client error
+func invalidRequest(err error) error {
+	return apperror.Wrap(err, apperror.KindInvalidArgument, "invalid_request", "invalid request")
+}
@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
+	"menu-parser/internal/domain/apperror"
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
 	"menu-parser/internal/transport/http/dto"
+	"menu-parser/internal/transport/http/middleware"
 	"menu-parser/internal/usecase"
 
 	"github.com/gin-gonic/gin"
@@ -22,13 +28,48 @@ func NewMenuHandler(menuUseCase *usecase.MenuUseCase) *MenuHandler {
 func (h *MenuHandler) ParseMenu(c *gin.Context) {
 	var req dto.ParseRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(invalidRequest(err))
 		return
 	}
 
-	taskID, err := h.menuUseCase.CreateParsingTask(c.Request.Context(), req.SpreadsheetID, req.RestaurantName)
+	input := &usecase.CreateParsingTaskInput{
+		RestaurantID:   req.RestaurantID,
+		SpreadsheetID:  req.SpreadsheetID,
+		RestaurantName: req.RestaurantName,
+		MappingProfile: req.MappingProfile,
+		Sheets:         req.Sheets,
+		AllSheets:      req.AllSheets,
+		Mode:           entity.ParseMode(req.Mode),
+		SkipActivation: req.Activate != nil && !*req.Activate,
+		CreatedBy:      c.GetString(middleware.UserIDKey),
+		CallbackURL:    req.CallbackURL,
+	}
+	// Tasks without a registered restaurant are scoped by the restaurant name
+	restaurantKey := req.RestaurantID
+	if restaurantKey == "" {
+		restaurantKey = req.RestaurantName
+	}
+	if !middleware.CanAccessRestaurant(c, restaurantKey, true) {
+		c.Error(middleware.ErrNoRestaurantAccess)
+		return
+	}
+
+	if req.ColumnMapping != nil {
+		input.ColumnMapping = req.ColumnMapping.ToEntity()
+		if err := input.ColumnMapping.Validate(); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	taskID, err := h.menuUseCase.CreateParsingTask(c.Request.Context(), input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		// A missing restaurant or profile named in the body makes the request
+		// invalid; it is not the resource the route looks up
+		if errors.Is(err, repository.ErrColumnMappingProfileNotFound) || errors.Is(err, repository.ErrRestaurantNotFound) {
+			err = apperror.Wrap(err, apperror.KindInvalidArgument, apperror.CodeOf(err), "invalid parse request")
+		}
+		c.Error(err)
 		return
 	}
 
@@ -38,12 +79,92 @@ func (h *MenuHandler) ParseMenu(c *gin.Context) {
 	})
 }
 
+func (h *MenuHandler) ListTasks(c *gin.Context) {
+	var query dto.TaskQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	filter := query.ToFilter()
+	// Scoped callers only see tasks of their own restaurants; without any the
+	// non-nil empty list matches no task
+	if principal := middleware.Principal(c); !principal.Unscoped() {
+		if query.RestaurantID != "" {
+			if !middleware.CanAccessRestaurant(c, query.RestaurantID, false) {
+				c.Error(middleware.ErrNoRestaurantAccess)
+				return
+			}
+		} else {
+			filter.RestaurantKeys = append([]string{}, principal.RestaurantIDs...)
+		}
+	}
+
+	tasks, nextCursor, err := h.menuUseCase.ListTasks(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	items := make([]*dto.TaskStatusResponse, 0, len(tasks))
+	for i := range tasks {
+		items = append(items, dto.ToTaskStatusResponse(&tasks[i]))
+	}
+
+	c.JSON(http.StatusOK, dto.TaskListResponse{
+		Items:      items,
+		NextCursor: nextCursor,
+	})
+}
+
 func (h *MenuHandler) GetTaskStatus(c *gin.Context) {
 	taskID := c.Param("task_id")
 
 	task, err := h.menuUseCase.GetTaskStatus(c.Request.Context(), taskID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(err)
+		return
+	}
+	if !middleware.CanAccessRestaurant(c, task.RestaurantKey(), false) {
+		c.Error(middleware.ErrNoRestaurantAccess)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ToTaskStatusResponse(task))
+}
+
+func (h *MenuHandler) CancelTask(c *gin.Context) {
+	h.changeTask(c, h.menuUseCase.CancelTask)
+}
+
+func (h *MenuHandler) RetryTask(c *gin.Context) {
+	h.changeTask(c, h.menuUseCase.RetryTask)
+}
+
+type taskChangeFunc func(ctx context.Context, taskID, userID string) (*entity.ParsingTask, error)
+
+// changeTask applies fn to the task after checking write access to its restaurant
+func (h *MenuHandler) changeTask(c *gin.Context, fn taskChangeFunc) {
+	taskID := c.Param("task_id")
+
+	task, err := h.menuUseCase.GetTaskStatus(c.Request.Context(), taskID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !middleware.CanAccessRestaurant(c, task.RestaurantKey(), true) {
+		c.Error(middleware.ErrNoRestaurantAccess)
+		return
+	}
+
+	userID := c.GetString(middleware.UserIDKey)
+	if userID == "" {
+		userID = "system"
+	}
+
+	task, err = fn(c.Request.Context(), taskID, userID)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -55,7 +176,66 @@ func (h *MenuHandler) GetMenu(c *gin.Context) {
 
 	menu, err := h.menuUseCase.GetMenu(c.Request.Context(), menuID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(err)
+		return
+	}
+	if !middleware.CanAccessRestaurant(c, menu.RestaurantID, false) {
+		c.Error(middleware.ErrNoRestaurantAccess)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ToMenuResponse(menu))
+}
+
+func (h *MenuHandler) GetMenuDiff(c *gin.Context) {
+	for _, menuID := range []string{c.Param("menu_id"), c.Query("against")} {
+		if menuID == "" {
+			continue
+		}
+		menu, err := h.menuUseCase.GetMenu(c.Request.Context(), menuID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if !middleware.CanAccessRestaurant(c, menu.RestaurantID, false) {
+			c.Error(middleware.ErrNoRestaurantAccess)
+			return
+		}
+	}
+
+	diff, err := h.menuUseCase.DiffMenus(c.Request.Context(), c.Param("menu_id"), c.Query("against"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+func (h *MenuHandler) ListMenuVersions(c *gin.Context) {
+	versions, err := h.menuUseCase.ListMenuVersions(c.Request.Context(), c.Param("restaurant_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+func (h *MenuHandler) ActivateMenu(c *gin.Context) {
+	menu, err := h.menuUseCase.ActivateMenu(c.Request.Context(), c.Param("restaurant_id"), c.Param("menu_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ToMenuResponse(menu))
+}
+
+func (h *MenuHandler) RollbackMenu(c *gin.Context) {
+	menu, err := h.menuUseCase.RollbackMenu(c.Request.Context(), c.Param("restaurant_id"))
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"menu-parser/internal/transport/http/dto"
+	"menu-parser/internal/transport/http/middleware"
 	"menu-parser/internal/usecase"
 
 	"github.com/gin-gonic/gin"
@@ -25,18 +26,18 @@ func (h *ProductHandler) UpdateProductStatus(c *gin.Context) {
 
 	var req dto.ProductStatusUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(invalidRequest(err))
 		return
 	}
 
-	userID := c.GetString("user_id")
+	userID := c.GetString(middleware.UserIDKey)
 	if userID == "" {
 		userID = "system"
 	}
 
 	err := h.productUseCase.UpdateProductStatus(c.Request.Context(), restaurantID, productID, req.Status, req.Reason, userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
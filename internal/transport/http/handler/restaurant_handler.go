@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net/http"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/transport/http/dto"
+	"menu-parser/internal/transport/http/middleware"
+	"menu-parser/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RestaurantHandler struct {
+	restaurantUseCase *usecase.RestaurantUseCase
+}
+
+func NewRestaurantHandler(restaurantUseCase *usecase.RestaurantUseCase) *RestaurantHandler {
+	return &RestaurantHandler{
+		restaurantUseCase: restaurantUseCase,
+	}
+}
+
+func (h *RestaurantHandler) CreateRestaurant(c *gin.Context) {
+	var req dto.RestaurantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	restaurant, err := h.restaurantUseCase.CreateRestaurant(c.Request.Context(), req.ToEntity(""))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, restaurant)
+}
+
+func (h *RestaurantHandler) ListRestaurants(c *gin.Context) {
+	restaurants, err := h.restaurantUseCase.ListRestaurants(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Scoped callers only see their own restaurants
+	visible := make([]entity.Restaurant, 0, len(restaurants))
+	for _, restaurant := range restaurants {
+		if middleware.CanAccessRestaurant(c, restaurant.ID, false) {
+			visible = append(visible, restaurant)
+		}
+	}
+
+	c.JSON(http.StatusOK, visible)
+}
+
+func (h *RestaurantHandler) GetRestaurant(c *gin.Context) {
+	restaurant, err := h.restaurantUseCase.GetRestaurant(c.Request.Context(), c.Param("restaurant_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, restaurant)
+}
+
+func (h *RestaurantHandler) UpdateRestaurant(c *gin.Context) {
+	var req dto.RestaurantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	restaurant, err := h.restaurantUseCase.UpdateRestaurant(c.Request.Context(), req.ToEntity(c.Param("restaurant_id")))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, restaurant)
+}
+
+func (h *RestaurantHandler) DeleteRestaurant(c *gin.Context) {
+	if err := h.restaurantUseCase.DeleteRestaurant(c.Request.Context(), c.Param("restaurant_id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"menu-parser/internal/domain/apperror"
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	principalKey = "principal"
+	// UserIDKey holds the subject of the principal, recorded as the user in audit records
+	UserIDKey = "user_id"
+
+	apiKeyHeader = "X-API-Key"
+)
+
+var (
+	ErrAuthenticationRequired = apperror.New(apperror.KindUnauthenticated, "authentication_required", "authentication required")
+	ErrInsufficientRole       = apperror.New(apperror.KindPermissionDenied, "insufficient_role", "insufficient role")
+	ErrNoRestaurantAccess     = apperror.New(apperror.KindPermissionDenied, "no_restaurant_access", "no access to restaurant")
+)
+
+// Authenticate requires an X-API-Key header or an Authorization: Bearer JWT
+// and stores the principal in the context
+func Authenticate(authenticator service.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var principal *entity.Principal
+		var err error
+
+		if apiKey := c.GetHeader(apiKeyHeader); apiKey != "" {
+			principal, err = authenticator.AuthenticateAPIKey(apiKey)
+		} else if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			principal, err = authenticator.AuthenticateToken(token)
+		} else {
+			abort(c, ErrAuthenticationRequired)
+			return
+		}
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.Set(principalKey, principal)
+		c.Set(UserIDKey, principal.Subject)
+		c.Next()
+	}
+}
+
+// RequireRole lets only principals with one of the roles through
+func RequireRole(roles ...entity.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := Principal(c)
+		for _, role := range roles {
+			if principal != nil && principal.Role == role {
+				c.Next()
+				return
+			}
+		}
+		abort(c, ErrInsufficientRole)
+	}
+}
+
+// RequireRestaurantAccess checks the :restaurant_id path parameter against the
+// principal's restaurants: read access for GET requests, write access otherwise
+func RequireRestaurantAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !CanAccessRestaurant(c, c.Param("restaurant_id"), c.Request.Method != http.MethodGet) {
+			abort(c, ErrNoRestaurantAccess)
+			return
+		}
+		c.Next()
+	}
+}
+
+// abort stops the chain; the Errors middleware writes the response
+func abort(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+// Principal returns the authenticated principal, nil on unauthenticated routes
+func Principal(c *gin.Context) *entity.Principal {
+	principal, _ := c.Get(principalKey)
+	p, _ := principal.(*entity.Principal)
+	return p
+}
+
+// CanAccessRestaurant reports whether the principal may read, or with write
+// change, data of the restaurant
+func CanAccessRestaurant(c *gin.Context, restaurantID string, write bool) bool {
+	principal := Principal(c)
+	if principal == nil {
+		return false
+	}
+	if write {
+		return principal.CanWrite(restaurantID)
+	}
+	return principal.CanRead(restaurantID)
+}
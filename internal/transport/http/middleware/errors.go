@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"menu-parser/internal/domain/apperror"
+	"menu-parser/internal/transport/http/dto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// RequestIDHeader carries the request ID; a client-supplied one is kept
+	RequestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+)
+
+// RequestID assigns every request an ID and echoes it in the response
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.New().String()
+		}
+
+		c.Set(requestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// RequestIDOf returns the ID assigned by RequestID
+func RequestIDOf(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// Errors writes the last error a handler attached with c.Error as the error
+// envelope, with the HTTP status derived from the error's kind
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		status := statusOf(err)
+		if status >= http.StatusInternalServerError {
+			log.Printf("Request %s %s %s failed: %v", RequestIDOf(c), c.Request.Method, c.Request.URL.Path, err)
+		}
+
+		c.JSON(status, dto.ErrorResponse{
+			Error: dto.ErrorBody{
+				Code:      apperror.CodeOf(err),
+				Message:   messageOf(err),
+				RequestID: RequestIDOf(c),
+			},
+		})
+	}
+}
+
+func statusOf(err error) int {
+	switch apperror.KindOf(err) {
+	case apperror.KindInvalidArgument:
+		return http.StatusBadRequest
+	case apperror.KindUnauthenticated:
+		return http.StatusUnauthorized
+	case apperror.KindPermissionDenied:
+		return http.StatusForbidden
+	case apperror.KindNotFound:
+		return http.StatusNotFound
+	case apperror.KindConflict:
+		return http.StatusConflict
+	case apperror.KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// messageOf returns the text shown to the client. Client errors are described
+// in full; for failures of the service only the message of the typed error is
+// shown, so driver and broker error strings do not leak.
+func messageOf(err error) string {
+	switch apperror.KindOf(err) {
+	case apperror.KindUnavailable, apperror.KindInternal:
+		return apperror.MessageOf(err)
+	default:
+		return err.Error()
+	}
+}
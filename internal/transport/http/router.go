@@ -1,31 +1,85 @@
 package http
 
 import (
+	"menu-parser/internal/domain/apperror"
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/service"
 	"menu-parser/internal/transport/http/handler"
+	"menu-parser/internal/transport/http/middleware"
 	"menu-parser/internal/usecase"
 
 	"github.com/gin-gonic/gin"
 )
 
+var errRouteNotFound = apperror.New(apperror.KindNotFound, "route_not_found", "route not found")
+
 func SetupRouter(
 	menuUseCase *usecase.MenuUseCase,
 	productUseCase *usecase.ProductUseCase,
+	mappingUseCase *usecase.ColumnMappingUseCase,
+	restaurantUseCase *usecase.RestaurantUseCase,
+	auditUseCase *usecase.AuditUseCase,
+	dlqUseCase *usecase.DLQUseCase,
 	healthUseCase *usecase.HealthUseCase,
+	authenticator service.Authenticator,
 ) *gin.Engine {
 	router := gin.Default()
+	router.Use(middleware.RequestID(), middleware.Errors())
+	router.NoRoute(func(c *gin.Context) {
+		c.Error(errRouteNotFound)
+	})
 
 	menuHandler := handler.NewMenuHandler(menuUseCase)
 	productHandler := handler.NewProductHandler(productUseCase)
+	mappingHandler := handler.NewColumnMappingHandler(mappingUseCase)
+	restaurantHandler := handler.NewRestaurantHandler(restaurantUseCase)
+	auditHandler := handler.NewAuditHandler(auditUseCase)
+	dlqHandler := handler.NewDLQHandler(dlqUseCase)
 	healthHandler := handler.NewHealthHandler(healthUseCase)
 
 	v1 := router.Group("/api/v1")
 	{
-		v1.POST("/parse", menuHandler.ParseMenu)
-		v1.GET("/parse/:task_id", menuHandler.GetTaskStatus)
-		v1.GET("/menu/:menu_id", menuHandler.GetMenu)
-		v1.PATCH("/restaurants/:restaurant_id/products/:product_id/status", productHandler.UpdateProductStatus)
 		v1.GET("/health", healthHandler.HealthCheck)
 	}
 
+	// Everything but the health check needs a principal; handlers of routes
+	// without a restaurant in the path check access to the loaded resource
+	api := v1.Group("", middleware.Authenticate(authenticator))
+	{
+		api.POST("/parse", menuHandler.ParseMenu)
+		api.GET("/parse", menuHandler.ListTasks)
+		api.GET("/parse/:task_id", menuHandler.GetTaskStatus)
+		api.POST("/parse/:task_id/cancel", menuHandler.CancelTask)
+		api.POST("/parse/:task_id/retry", menuHandler.RetryTask)
+		api.GET("/menu/:menu_id", menuHandler.GetMenu)
+		api.GET("/menu/:menu_id/diff", menuHandler.GetMenuDiff)
+		api.POST("/restaurants", middleware.RequireRole(entity.RoleAdmin), restaurantHandler.CreateRestaurant)
+		api.GET("/restaurants", restaurantHandler.ListRestaurants)
+	}
+
+	restaurant := api.Group("/restaurants/:restaurant_id", middleware.RequireRestaurantAccess())
+	{
+		restaurant.GET("", restaurantHandler.GetRestaurant)
+		restaurant.PUT("", restaurantHandler.UpdateRestaurant)
+		restaurant.DELETE("", middleware.RequireRole(entity.RoleAdmin), restaurantHandler.DeleteRestaurant)
+		restaurant.PATCH("/products/:product_id/status", productHandler.UpdateProductStatus)
+		restaurant.GET("/products/:product_id/audit", auditHandler.ListProductAudit)
+		restaurant.GET("/audit", auditHandler.ListRestaurantAudit)
+		restaurant.GET("/menus", menuHandler.ListMenuVersions)
+		restaurant.POST("/menus/rollback", menuHandler.RollbackMenu)
+		restaurant.POST("/menus/:menu_id/activate", menuHandler.ActivateMenu)
+		restaurant.GET("/column-mappings", mappingHandler.ListProfiles)
+		restaurant.GET("/column-mappings/:name", mappingHandler.GetProfile)
+		restaurant.PUT("/column-mappings/:name", mappingHandler.SaveProfile)
+		restaurant.DELETE("/column-mappings/:name", mappingHandler.DeleteProfile)
+	}
+
+	admin := api.Group("/admin", middleware.RequireRole(entity.RoleAdmin))
+	{
+		admin.GET("/dlq", dlqHandler.ListDeadLetters)
+		admin.POST("/dlq/replay", dlqHandler.ReplayDeadLetters)
+		admin.POST("/dlq/purge", dlqHandler.PurgeDeadLetters)
+	}
+
 	return router
 }
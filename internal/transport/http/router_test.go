@@ -0,0 +1,181 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+	"menu-parser/internal/usecase"
+	"menu-parser/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testAPIKeys = "admin-key=alice:admin," +
+	"operator-key=bob:operator:rest-1," +
+	"other-operator-key=carol:operator:rest-2|rest-3," +
+	"readonly-key=dave:readonly:rest-1," +
+	"global-readonly-key=erin:readonly:*"
+
+// Repositories holding one task and one menu of rest-1; methods the routes
+// under test do not reach panic through the embedded interfaces
+
+type fakeTaskRepo struct {
+	repository.TaskRepository
+	mu      sync.Mutex
+	filters []repository.TaskFilter
+}
+
+func (r *fakeTaskRepo) GetByID(ctx context.Context, taskID string) (*entity.ParsingTask, error) {
+	if taskID != "task-1" {
+		return nil, repository.ErrTaskNotFound
+	}
+	return &entity.ParsingTask{ID: taskID, RestaurantID: "rest-1", RestaurantName: "Кафе", Status: entity.TaskStatusQueued}, nil
+}
+
+func (r *fakeTaskRepo) List(ctx context.Context, filter repository.TaskFilter) ([]entity.ParsingTask, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.filters = append(r.filters, filter)
+	return nil, "", nil
+}
+
+func (r *fakeTaskRepo) lastFilter() repository.TaskFilter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.filters[len(r.filters)-1]
+}
+
+type fakeMenuRepo struct {
+	repository.MenuRepository
+	menuID primitive.ObjectID
+}
+
+func (r *fakeMenuRepo) GetByID(ctx context.Context, menuID string) (*entity.Menu, error) {
+	if menuID != r.menuID.Hex() {
+		return nil, repository.ErrMenuNotFound
+	}
+	return &entity.Menu{ID: r.menuID, RestaurantID: "rest-1", Name: "Меню"}, nil
+}
+
+func newTestRouter(t *testing.T) (*gin.Engine, *fakeTaskRepo, primitive.ObjectID) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	authenticator, err := auth.NewAuthenticator(testAPIKeys, "")
+	if err != nil {
+		t.Fatalf("new authenticator: %v", err)
+	}
+	tasks := &fakeTaskRepo{}
+	menus := &fakeMenuRepo{menuID: primitive.NewObjectID()}
+	menuUseCase := usecase.NewMenuUseCase(menus, tasks, nil, nil, nil, nil, nil, nil, true)
+
+	router := SetupRouter(menuUseCase, nil, nil, nil, nil, nil, nil, authenticator)
+	return router, tasks, menus.menuID
+}
+
+func serve(router *gin.Engine, method, path, apiKey, body string) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRouterAccessControl(t *testing.T) {
+	router, _, menuID := newTestRouter(t)
+	menuPath := "/api/v1/menu/" + menuID.Hex()
+	parseBody := `{"restaurant_id":"rest-1","spreadsheet_id":"sheet"}`
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		apiKey string
+		body   string
+		want   int
+	}{
+		{"parse without credentials", http.MethodPost, "/api/v1/parse", "", parseBody, http.StatusUnauthorized},
+		{"parse with unknown key", http.MethodPost, "/api/v1/parse", "unknown-key", parseBody, http.StatusUnauthorized},
+		{"parse by readonly", http.MethodPost, "/api/v1/parse", "readonly-key", parseBody, http.StatusForbidden},
+		{"parse by global readonly", http.MethodPost, "/api/v1/parse", "global-readonly-key", parseBody, http.StatusForbidden},
+		{"parse by operator of another restaurant", http.MethodPost, "/api/v1/parse", "other-operator-key", parseBody, http.StatusForbidden},
+		{"parse by restaurant name outside scope", http.MethodPost, "/api/v1/parse", "operator-key", `{"restaurant_name":"Кафе","spreadsheet_id":"sheet"}`, http.StatusForbidden},
+
+		{"task without credentials", http.MethodGet, "/api/v1/parse/task-1", "", "", http.StatusUnauthorized},
+		{"task by admin", http.MethodGet, "/api/v1/parse/task-1", "admin-key", "", http.StatusOK},
+		{"task by operator", http.MethodGet, "/api/v1/parse/task-1", "operator-key", "", http.StatusOK},
+		{"task by readonly", http.MethodGet, "/api/v1/parse/task-1", "readonly-key", "", http.StatusOK},
+		{"task by global readonly", http.MethodGet, "/api/v1/parse/task-1", "global-readonly-key", "", http.StatusOK},
+		{"task by operator of another restaurant", http.MethodGet, "/api/v1/parse/task-1", "other-operator-key", "", http.StatusForbidden},
+		{"cancel by readonly", http.MethodPost, "/api/v1/parse/task-1/cancel", "readonly-key", "", http.StatusForbidden},
+		{"retry by operator of another restaurant", http.MethodPost, "/api/v1/parse/task-1/retry", "other-operator-key", "", http.StatusForbidden},
+
+		{"menu without credentials", http.MethodGet, menuPath, "", "", http.StatusUnauthorized},
+		{"menu by admin", http.MethodGet, menuPath, "admin-key", "", http.StatusOK},
+		{"menu by readonly", http.MethodGet, menuPath, "readonly-key", "", http.StatusOK},
+		{"menu by operator of another restaurant", http.MethodGet, menuPath, "other-operator-key", "", http.StatusForbidden},
+
+		{"restaurant by operator of another restaurant", http.MethodGet, "/api/v1/restaurants/rest-1", "other-operator-key", "", http.StatusForbidden},
+		{"status change by readonly", http.MethodPatch, "/api/v1/restaurants/rest-1/products/p1/status", "readonly-key", `{"status":"not_available"}`, http.StatusForbidden},
+		{"restaurant creation by operator", http.MethodPost, "/api/v1/restaurants", "operator-key", `{}`, http.StatusForbidden},
+		{"dead letters by operator", http.MethodGet, "/api/v1/admin/dlq", "operator-key", "", http.StatusForbidden},
+		{"dead letters by global readonly", http.MethodGet, "/api/v1/admin/dlq", "global-readonly-key", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(router, tt.method, tt.path, tt.apiKey, tt.body); got != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterScopesTaskListing(t *testing.T) {
+	router, tasks, _ := newTestRouter(t)
+
+	tests := []struct {
+		name   string
+		apiKey string
+		query  string
+		want   int
+		// keys is the restaurant scope passed to the repository; nil is unscoped
+		keys []string
+	}{
+		{"admin", "admin-key", "", http.StatusOK, nil},
+		{"global readonly", "global-readonly-key", "", http.StatusOK, nil},
+		{"operator", "operator-key", "", http.StatusOK, []string{"rest-1"}},
+		{"operator of several restaurants", "other-operator-key", "", http.StatusOK, []string{"rest-2", "rest-3"}},
+		{"operator filtering its restaurant", "operator-key", "?restaurant_id=rest-1", http.StatusOK, []string{"rest-1"}},
+		{"operator filtering another restaurant", "other-operator-key", "?restaurant_id=rest-1", http.StatusForbidden, nil},
+		{"readonly filtering another restaurant", "readonly-key", "?restaurant_id=rest-2", http.StatusForbidden, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks.mu.Lock()
+			tasks.filters = nil
+			tasks.mu.Unlock()
+
+			if got := serve(router, http.MethodGet, "/api/v1/parse"+tt.query, tt.apiKey, ""); got != tt.want {
+				t.Fatalf("GET /api/v1/parse%s = %d, want %d", tt.query, got, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			keys := tasks.lastFilter().RestaurantKeys
+			if (keys == nil) != (tt.keys == nil) || !slices.Equal(keys, tt.keys) {
+				t.Errorf("restaurant keys = %#v, want %#v", keys, tt.keys)
+			}
+		})
+	}
+}
@@ -3,28 +3,36 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"hash/fnv"
 	"log"
-	"math"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"menu-parser/internal/domain/apperror"
 	"menu-parser/internal/domain/entity"
 	"menu-parser/internal/domain/repository"
 	"menu-parser/internal/domain/service"
 	"menu-parser/internal/usecase"
 )
 
-const maxRetries = 3
+// MaxRetries is how many times a parsing task is retried before it fails
+const MaxRetries = 3
 
 type Consumer struct {
 	menuUseCase    *usecase.MenuUseCase
 	productUseCase *usecase.ProductUseCase
 	taskRepo       repository.TaskRepository
 	queueConsumer  service.QueueConsumer
-	ctx            context.Context
-	cancel         context.CancelFunc
+	// Number of menu parsing tasks and of product status events handled at once
+	menuParsingWorkers   int
+	productStatusWorkers int
+	ctx                  context.Context
+	cancel               context.CancelFunc
+	workers              sync.WaitGroup
 }
 
 func NewConsumer(
@@ -32,25 +40,26 @@ func NewConsumer(
 	productUseCase *usecase.ProductUseCase,
 	taskRepo repository.TaskRepository,
 	queueConsumer service.QueueConsumer,
+	menuParsingWorkers int,
+	productStatusWorkers int,
 ) *Consumer {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Consumer{
-		menuUseCase:    menuUseCase,
-		productUseCase: productUseCase,
-		taskRepo:       taskRepo,
-		queueConsumer:  queueConsumer,
-		ctx:            ctx,
-		cancel:         cancel,
+		menuUseCase:          menuUseCase,
+		productUseCase:       productUseCase,
+		taskRepo:             taskRepo,
+		queueConsumer:        queueConsumer,
+		menuParsingWorkers:   max(menuParsingWorkers, 1),
+		productStatusWorkers: max(productStatusWorkers, 1),
+		ctx:                  ctx,
+		cancel:               cancel,
 	}
 }
 
+// Start runs the consumer until SIGINT or SIGTERM
 func (c *Consumer) Start() {
-	log.Println("Queue consumer started")
-
-	go c.processMenuParsingTasks()
-
-	go c.processProductStatusEvents()
+	c.Run()
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -60,6 +69,18 @@ func (c *Consumer) Start() {
 	c.Shutdown()
 }
 
+// Run starts the workers and returns; they stop on Shutdown
+func (c *Consumer) Run() {
+	log.Printf("Queue consumer started with %d menu parsing and %d product status workers",
+		c.menuParsingWorkers, c.productStatusWorkers)
+
+	c.processMenuParsingTasks()
+
+	c.processProductStatusEvents()
+}
+
+// processMenuParsingTasks starts a pool of workers taking tasks from the
+// queue; tasks are independent, so any worker can handle any of them
 func (c *Consumer) processMenuParsingTasks() {
 	msgs, err := c.queueConsumer.ConsumeMenuParsingTasks()
 	if err != nil {
@@ -67,16 +88,12 @@ func (c *Consumer) processMenuParsingTasks() {
 		return
 	}
 
-	for {
-		select {
-		case <-c.ctx.Done():
-			return
-		case msg, ok := <-msgs:
-			if !ok {
-				return
-			}
-			c.handleMenuParsingTask(msg)
-		}
+	for i := 0; i < c.menuParsingWorkers; i++ {
+		c.workers.Add(1)
+		go func() {
+			defer c.workers.Done()
+			c.handleMessages(msgs, c.handleMenuParsingTask)
+		}()
 	}
 }
 
@@ -84,14 +101,14 @@ func (c *Consumer) handleMenuParsingTask(msg service.Message) {
 	var message map[string]string
 	if err := json.Unmarshal(msg.Body, &message); err != nil {
 		log.Printf("Error unmarshaling message: %v", err)
-		c.queueConsumer.NackMessage(msg.DeliveryTag, false)
+		msg.Nack(false)
 		return
 	}
 
 	taskID := message["task_id"]
 	if taskID == "" {
 		log.Printf("Empty task_id in message")
-		c.queueConsumer.NackMessage(msg.DeliveryTag, false)
+		msg.Nack(false)
 		return
 	}
 
@@ -102,43 +119,77 @@ func (c *Consumer) handleMenuParsingTask(msg service.Message) {
 	task, err := c.taskRepo.GetByID(ctx, taskID)
 	if err != nil {
 		log.Printf("Error getting task %s: %v", taskID, err)
-		c.queueConsumer.NackMessage(msg.DeliveryTag, true) // Requeue
+		// Requeue unless the task does not exist at all
+		msg.Nack(!apperror.IsPermanent(err))
+		return
+	}
+
+	// The outbox delivers at least once, so the same task can arrive again
+	if task.Status == entity.TaskStatusCompleted {
+		log.Printf("Task %s already completed, skipping duplicate message", taskID)
+		msg.Ack()
+		return
+	}
+
+	if task.Status == entity.TaskStatusCancelled {
+		log.Printf("Task %s was cancelled, skipping", taskID)
+		msg.Ack()
 		return
 	}
 
 	// Check retry count
-	if task.RetryCount >= maxRetries {
+	if task.RetryCount >= MaxRetries {
 		log.Printf("Task %s exceeded max retries", taskID)
-		c.taskRepo.UpdateStatus(ctx, taskID, entity.TaskStatusFailed, nil, "Max retries exceeded")
-		c.queueConsumer.NackMessage(msg.DeliveryTag, false) // Don't requeue, goes to DLQ
+		if err := c.menuUseCase.FailTask(ctx, task, "max_retries_exceeded", "Max retries exceeded"); err != nil {
+			log.Printf("Error failing task %s: %v", taskID, err)
+		}
+		msg.Nack(false) // Don't requeue, goes to DLQ
 		return
 	}
 
 	// Process menu parsing
 	err = c.menuUseCase.ProcessMenuParsing(ctx, taskID)
+	if errors.Is(err, usecase.ErrTaskNotQueued) {
+		log.Printf("Task %s is not queued, skipping duplicate message", taskID)
+		msg.Ack()
+		return
+	}
+	if errors.Is(err, usecase.ErrTaskCancelled) {
+		log.Printf("Task %s was cancelled during processing", taskID)
+		msg.Ack()
+		return
+	}
+	if apperror.IsPermanent(err) {
+		// Retrying cannot help, and the use case has already failed the task with its error code
+		log.Printf("Task %s failed permanently (%s): %v", taskID, apperror.CodeOf(err), err)
+		msg.Ack()
+		return
+	}
 	if err != nil {
-		log.Printf("Error processing menu parsing: %v", err)
+		log.Printf("Error processing menu parsing (attempt %d): %v", msg.Attempt+1, err)
 
 		// Increment retry count
 		c.taskRepo.IncrementRetryCount(ctx, taskID)
 
-		// Calculate exponential backoff delay
-		delay := time.Duration(math.Pow(2, float64(task.RetryCount))) * time.Second
-		time.Sleep(delay)
-
-		// Update status and requeue
-		c.taskRepo.UpdateStatus(ctx, taskID, entity.TaskStatusQueued, nil, err.Error())
-		c.queueConsumer.NackMessage(msg.DeliveryTag, true) // Requeue for retry
+		// Update status and route through a retry queue, which redelivers the
+		// task after an exponential backoff delay without holding this consumer
+		c.taskRepo.UpdateStatus(ctx, taskID, entity.TaskStatusQueued, nil, apperror.MessageOf(err))
+		if err := msg.Retry(); err != nil {
+			log.Printf("Error scheduling retry of task %s: %v", taskID, err)
+			msg.Nack(true) // Requeue immediately instead
+		}
 		return
 	}
 
 	// ACK message after successful processing
-	if err := c.queueConsumer.AckMessage(msg.DeliveryTag); err != nil {
+	if err := msg.Ack(); err != nil {
 		log.Printf("Error ACKing message: %v", err)
 	}
 	log.Printf("Successfully processed task %s", taskID)
 }
 
+// processProductStatusEvents shards events across workers by restaurant and
+// product, so events of the same product are still applied in order
 func (c *Consumer) processProductStatusEvents() {
 	msgs, err := c.queueConsumer.ConsumeProductStatusEvents()
 	if err != nil {
@@ -146,6 +197,57 @@ func (c *Consumer) processProductStatusEvents() {
 		return
 	}
 
+	shards := make([]chan service.Message, c.productStatusWorkers)
+	for i := range shards {
+		shards[i] = make(chan service.Message)
+		c.workers.Add(1)
+		go func(shard <-chan service.Message) {
+			defer c.workers.Done()
+			c.handleMessages(shard, c.handleProductStatusEvent)
+		}(shards[i])
+	}
+
+	go func() {
+		defer func() {
+			for _, shard := range shards {
+				close(shard)
+			}
+		}()
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				select {
+				case shards[productShard(msg, len(shards))] <- msg:
+				case <-c.ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+}
+
+// productShard picks the shard of an event by restaurant and product; events
+// that cannot be decoded go to the first shard, which rejects them
+func productShard(msg service.Message, shards int) int {
+	var key struct {
+		RestaurantID string `json:"restaurant_id"`
+		ProductID    string `json:"product_id"`
+	}
+	if err := json.Unmarshal(msg.Body, &key); err != nil {
+		return 0
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(key.RestaurantID + "/" + key.ProductID))
+	return int(hash.Sum32() % uint32(shards))
+}
+
+func (c *Consumer) handleMessages(msgs <-chan service.Message, handle func(service.Message)) {
 	for {
 		select {
 		case <-c.ctx.Done():
@@ -154,7 +256,7 @@ func (c *Consumer) processProductStatusEvents() {
 			if !ok {
 				return
 			}
-			c.handleProductStatusEvent(msg)
+			handle(msg)
 		}
 	}
 }
@@ -163,7 +265,7 @@ func (c *Consumer) handleProductStatusEvent(msg service.Message) {
 	var event entity.ProductStatusChangeEvent
 	if err := json.Unmarshal(msg.Body, &event); err != nil {
 		log.Printf("Error unmarshaling event: %v", err)
-		c.queueConsumer.NackMessage(msg.DeliveryTag, false)
+		msg.Nack(false)
 		return
 	}
 
@@ -171,13 +273,14 @@ func (c *Consumer) handleProductStatusEvent(msg service.Message) {
 	defer cancel()
 
 	if err := c.productUseCase.ProcessProductStatusEvent(ctx, &event); err != nil {
-		log.Printf("Error processing product status event: %v", err)
-		c.queueConsumer.NackMessage(msg.DeliveryTag, true) // Requeue
+		log.Printf("Error processing product status event (%s): %v", apperror.CodeOf(err), err)
+		// Permanent errors such as an unknown product go to the DLQ instead of looping
+		msg.Nack(!apperror.IsPermanent(err))
 		return
 	}
 
 	// ACK message after successful processing
-	if err := c.queueConsumer.AckMessage(msg.DeliveryTag); err != nil {
+	if err := msg.Ack(); err != nil {
 		log.Printf("Error ACKing message: %v", err)
 	}
 	log.Printf("Processed product status event for product %s: %s -> %s",
@@ -188,7 +291,16 @@ func (c *Consumer) Shutdown() {
 	c.cancel()
 
 	// Give workers time to finish current tasks
-	time.Sleep(5 * time.Second)
+	done := make(chan struct{})
+	go func() {
+		c.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		log.Println("Timed out waiting for workers to finish")
+	}
 
 	log.Println("Queue consumer stopped")
 }
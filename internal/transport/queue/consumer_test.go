@@ -0,0 +1,544 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"menu-parser/internal/domain/apperror"
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/service"
+	"menu-parser/internal/usecase"
+	"menu-parser/pkg/config"
+	rabbitmqQueue "menu-parser/pkg/queue"
+	"menu-parser/pkg/webhook"
+)
+
+const (
+	testRestaurant    = "Кафе"
+	testWebhookSecret = "secret"
+)
+
+var (
+	errSheetsUnavailable   = apperror.New(apperror.KindUnavailable, "sheets_unavailable", "Sheets API unavailable")
+	errDatabaseUnavailable = apperror.New(apperror.KindUnavailable, "database_unavailable", "database unavailable")
+)
+
+// pipeline wires the use cases, the outbox relay and the consumer to fake
+// repositories and the in-memory queue, the way the API runs with
+// QUEUE_BACKEND=memory
+type pipeline struct {
+	menuUseCase    *usecase.MenuUseCase
+	productUseCase *usecase.ProductUseCase
+	tasks          *fakeTaskRepo
+	menus          *fakeMenuRepo
+	audits         *fakeAuditRepo
+	outbox         *fakeOutboxRepo
+	queue          *rabbitmqQueue.MemoryQueue
+	parser         *fakeParser
+	// webhooks delivers task callbacks once set
+	webhooks service.WebhookSender
+}
+
+func newPipeline(parse func(ctx context.Context, call int, req *service.ParseMenuRequest) (*entity.Menu, error)) *pipeline {
+	p := &pipeline{
+		tasks:  newFakeTaskRepo(),
+		menus:  newFakeMenuRepo(),
+		audits: &fakeAuditRepo{},
+		outbox: newFakeOutboxRepo(),
+		queue: rabbitmqQueue.NewMemoryQueue(&config.Config{
+			RabbitMQMenuParsingQueue:   "menu-parsing",
+			RabbitMQProductStatusQueue: "product-status",
+			RabbitMQDLQQueue:           "dlq",
+			RabbitMQRetryDelays:        []time.Duration{10 * time.Millisecond},
+		}),
+		parser: &fakeParser{parse: parse},
+	}
+	p.menuUseCase = usecase.NewMenuUseCase(p.menus, p.tasks, fakeMappingRepo{}, p.audits, nil, p.outbox, fakeTransactor{}, p.parser, true)
+	p.productUseCase = usecase.NewProductUseCase(p.menus, p.audits, p.outbox)
+	return p
+}
+
+// start runs the outbox relay and the consumer until the test ends
+func (p *pipeline) start(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	relay := usecase.NewOutboxRelay(p.outbox, p.tasks, p.queue, p.webhooks, 5*time.Millisecond, 10)
+	go relay.Run(ctx)
+
+	consumer := NewConsumer(p.menuUseCase, p.productUseCase, p.tasks, p.queue, 2, 2)
+	consumer.Run()
+
+	t.Cleanup(func() {
+		cancel()
+		consumer.Shutdown()
+		p.queue.Close()
+	})
+}
+
+func (p *pipeline) createTask(t *testing.T) string {
+	t.Helper()
+	return p.createTaskWith(t, nil)
+}
+
+func (p *pipeline) createTaskWithCallback(t *testing.T, callbackURL string) string {
+	t.Helper()
+	return p.createTaskWith(t, func(input *usecase.CreateParsingTaskInput) {
+		input.CallbackURL = callbackURL
+	})
+}
+
+// createTaskWith creates a task of the test restaurant, adjusted by configure
+func (p *pipeline) createTaskWith(t *testing.T, configure func(input *usecase.CreateParsingTaskInput)) string {
+	t.Helper()
+	input := &usecase.CreateParsingTaskInput{
+		SpreadsheetID:  "spreadsheet",
+		RestaurantName: testRestaurant,
+		CreatedBy:      "tester",
+	}
+	if configure != nil {
+		configure(input)
+	}
+	taskID, err := p.menuUseCase.CreateParsingTask(context.Background(), input)
+	if err != nil {
+		t.Fatalf("create parsing task: %v", err)
+	}
+	return taskID
+}
+
+func (p *pipeline) task(t *testing.T, taskID string) *entity.ParsingTask {
+	t.Helper()
+	task, err := p.tasks.GetByID(context.Background(), taskID)
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	return task
+}
+
+func (p *pipeline) deadLetters(t *testing.T) []entity.DeadLetter {
+	t.Helper()
+	letters, err := p.queue.ListDeadLetters(100)
+	if err != nil {
+		t.Fatalf("list dead letters: %v", err)
+	}
+	return letters
+}
+
+// seedMenu stores an active menu of the test restaurant with available products
+func (p *pipeline) seedMenu(t *testing.T, productIDs ...string) {
+	t.Helper()
+	menu := &entity.Menu{RestaurantID: testRestaurant, Name: "Меню"}
+	for _, id := range productIDs {
+		menu.Products = append(menu.Products, entity.Product{ExtID: id, Name: id, Status: string(entity.ProductStatusAvailable)})
+	}
+	ctx := context.Background()
+	saved, err := p.menus.Create(ctx, menu)
+	if err != nil {
+		t.Fatalf("create menu: %v", err)
+	}
+	if _, err := p.menus.Activate(ctx, testRestaurant, saved.ID.Hex()); err != nil {
+		t.Fatalf("activate menu: %v", err)
+	}
+}
+
+func parsedMenu(req *service.ParseMenuRequest) *entity.Menu {
+	return &entity.Menu{
+		Name:         req.RestaurantName,
+		RestaurantID: req.RestaurantID,
+		Products: []entity.Product{
+			{ExtID: "p1", Name: "Суп", Price: 200, Status: string(entity.ProductStatusAvailable)},
+		},
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// settle gives a wrongly redelivered message time to show up
+func settle() {
+	time.Sleep(100 * time.Millisecond)
+}
+
+func TestPipelineAcksCompletedTask(t *testing.T) {
+	p := newPipeline(func(ctx context.Context, call int, req *service.ParseMenuRequest) (*entity.Menu, error) {
+		return parsedMenu(req), nil
+	})
+	taskID := p.createTask(t)
+	p.start(t)
+
+	waitFor(t, "task to complete", func() bool {
+		return p.task(t, taskID).Status == entity.TaskStatusCompleted
+	})
+	settle()
+
+	task := p.task(t, taskID)
+	active, _ := p.menus.GetActiveByRestaurant(context.Background(), testRestaurant)
+	if active == nil || task.MenuID == nil || *task.MenuID != active.ID {
+		t.Errorf("task menu %v is not the active menu %+v", task.MenuID, active)
+	}
+	if calls := p.parser.callCount(); calls != 1 {
+		t.Errorf("parser called %d times, want 1", calls)
+	}
+	if letters := p.deadLetters(t); len(letters) != 0 {
+		t.Errorf("dead-letter queue holds %d messages, want none", len(letters))
+	}
+}
+
+func TestPipelineRetriesTransientFailure(t *testing.T) {
+	p := newPipeline(func(ctx context.Context, call int, req *service.ParseMenuRequest) (*entity.Menu, error) {
+		if call == 1 {
+			return nil, errSheetsUnavailable
+		}
+		return parsedMenu(req), nil
+	})
+	taskID := p.createTask(t)
+	p.start(t)
+
+	waitFor(t, "task to complete", func() bool {
+		return p.task(t, taskID).Status == entity.TaskStatusCompleted
+	})
+	settle()
+
+	task := p.task(t, taskID)
+	if task.RetryCount != 1 {
+		t.Errorf("retry count = %d, want 1", task.RetryCount)
+	}
+	if calls := p.parser.callCount(); calls != 2 {
+		t.Errorf("parser called %d times, want 2", calls)
+	}
+	if letters := p.deadLetters(t); len(letters) != 0 {
+		t.Errorf("dead-letter queue holds %d messages, want none", len(letters))
+	}
+}
+
+func TestPipelineDeadLettersTaskAfterMaxRetries(t *testing.T) {
+	p := newPipeline(func(ctx context.Context, call int, req *service.ParseMenuRequest) (*entity.Menu, error) {
+		return nil, errSheetsUnavailable
+	})
+	taskID := p.createTask(t)
+	p.start(t)
+
+	waitFor(t, "task to be dead-lettered", func() bool {
+		return len(p.deadLetters(t)) == 1
+	})
+
+	task := p.task(t, taskID)
+	if task.Status != entity.TaskStatusFailed || task.ErrorCode != "max_retries_exceeded" {
+		t.Errorf("task is %s (%s), want failed (max_retries_exceeded)", task.Status, task.ErrorCode)
+	}
+	if calls := p.parser.callCount(); calls != MaxRetries {
+		t.Errorf("parser called %d times, want %d", calls, MaxRetries)
+	}
+	letter := p.deadLetters(t)[0]
+	if letter.SourceQueue != "menu-parsing" || letter.TaskID != taskID {
+		t.Errorf("dead letter from %q for task %q, want menu-parsing and %q", letter.SourceQueue, letter.TaskID, taskID)
+	}
+}
+
+func TestPipelineRetriesMergeAfterCompletionFailure(t *testing.T) {
+	p := newPipeline(func(ctx context.Context, call int, req *service.ParseMenuRequest) (*entity.Menu, error) {
+		menu := parsedMenu(req)
+		menu.Products[0].Price = float64(100 + call)
+		return menu, nil
+	})
+	p.seedMenu(t, "p1")
+	// The first merge is stored, but the task cannot be completed and is retried
+	p.tasks.completeFailures = 1
+	taskID := p.createTaskWith(t, func(input *usecase.CreateParsingTaskInput) {
+		input.Mode = entity.ParseModeMerge
+	})
+	p.start(t)
+
+	waitFor(t, "task to complete", func() bool {
+		return p.task(t, taskID).Status == entity.TaskStatusCompleted
+	})
+	settle()
+
+	if calls := p.parser.callCount(); calls != 2 {
+		t.Errorf("parser called %d times, want 2", calls)
+	}
+	active, _ := p.menus.GetActiveByRestaurant(context.Background(), testRestaurant)
+	if active == nil || len(active.Products) != 1 || active.Products[0].Price != 102 {
+		t.Errorf("active menu = %+v, want p1 priced 102", active)
+	}
+	audits := p.audits.byProduct("p1")
+	if len(audits) != 2 || audits[0].CorrelationID == audits[1].CorrelationID {
+		t.Errorf("audit records = %+v, want one per merge with distinct correlation IDs", audits)
+	}
+	for _, audit := range audits {
+		if !strings.HasPrefix(audit.CorrelationID, taskID+":") {
+			t.Errorf("audit correlation ID %q does not name task %s", audit.CorrelationID, taskID)
+		}
+	}
+	if letters := p.deadLetters(t); len(letters) != 0 {
+		t.Errorf("dead-letter queue holds %d messages, want none", len(letters))
+	}
+}
+
+func TestPipelineAcksPermanentFailure(t *testing.T) {
+	p := newPipeline(func(ctx context.Context, call int, req *service.ParseMenuRequest) (*entity.Menu, error) {
+		cause := errors.New("googleapi: Error 404: Requested entity was not found., notFound")
+		return nil, apperror.Wrap(cause, apperror.KindNotFound, "spreadsheet_not_found", "unable to get spreadsheet metadata")
+	})
+	taskID := p.createTask(t)
+	p.start(t)
+
+	waitFor(t, "task to fail", func() bool {
+		return p.task(t, taskID).Status == entity.TaskStatusFailed
+	})
+	settle()
+
+	// Only the typed message is stored, not the wrapped API error
+	task := p.task(t, taskID)
+	if task.ErrorCode != "spreadsheet_not_found" || task.ErrorMessage != "unable to get spreadsheet metadata" {
+		t.Errorf("task error = %s: %q, want spreadsheet_not_found: %q", task.ErrorCode, task.ErrorMessage, "unable to get spreadsheet metadata")
+	}
+	if calls := p.parser.callCount(); calls != 1 {
+		t.Errorf("parser called %d times, want 1", calls)
+	}
+	if letters := p.deadLetters(t); len(letters) != 0 {
+		t.Errorf("dead-letter queue holds %d messages, want none", len(letters))
+	}
+}
+
+func TestPipelineStopsParsingOfCancelledTask(t *testing.T) {
+	parsing := make(chan struct{})
+	parseErr := make(chan error, 1)
+	p := newPipeline(func(ctx context.Context, call int, req *service.ParseMenuRequest) (*entity.Menu, error) {
+		close(parsing)
+		<-ctx.Done()
+		parseErr <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	taskID := p.createTask(t)
+	p.start(t)
+
+	<-parsing
+	if _, err := p.menuUseCase.CancelTask(context.Background(), taskID, "tester"); err != nil {
+		t.Fatalf("cancel task: %v", err)
+	}
+
+	select {
+	case err := <-parseErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("parse context error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("parser was not stopped after the task was cancelled")
+	}
+	settle()
+
+	if task := p.task(t, taskID); task.Status != entity.TaskStatusCancelled || task.ErrorCode != "" {
+		t.Errorf("task is %s (%s), want cancelled without an error", task.Status, task.ErrorCode)
+	}
+	if calls := p.parser.callCount(); calls != 1 {
+		t.Errorf("parser called %d times, want 1", calls)
+	}
+	if letters := p.deadLetters(t); len(letters) != 0 {
+		t.Errorf("dead-letter queue holds %d messages, want none", len(letters))
+	}
+}
+
+func TestPipelineKeepsTaskCancelledAfterMenuWasSaved(t *testing.T) {
+	p := newPipeline(func(ctx context.Context, call int, req *service.ParseMenuRequest) (*entity.Menu, error) {
+		return parsedMenu(req), nil
+	})
+	taskID := p.createTaskWithCallback(t, "https://example.com/hook")
+	p.menus.onActivate = func() {
+		if _, err := p.tasks.Cancel(context.Background(), taskID, "tester"); err != nil {
+			t.Errorf("cancel task: %v", err)
+		}
+	}
+	p.start(t)
+
+	waitFor(t, "menu to be saved", func() bool {
+		active, _ := p.menus.GetActiveByRestaurant(context.Background(), testRestaurant)
+		return active != nil
+	})
+	settle()
+
+	if task := p.task(t, taskID); task.Status != entity.TaskStatusCancelled || task.MenuID != nil {
+		t.Errorf("task is %s with menu %v, want cancelled without a menu", task.Status, task.MenuID)
+	}
+	if webhooks := p.outbox.withType(entity.OutboxMessageTaskWebhook); len(webhooks) != 0 {
+		t.Errorf("queued %d webhooks for the cancelled task, want none", len(webhooks))
+	}
+	if calls := p.parser.callCount(); calls != 1 {
+		t.Errorf("parser called %d times, want 1", calls)
+	}
+	if letters := p.deadLetters(t); len(letters) != 0 {
+		t.Errorf("dead-letter queue holds %d messages, want none", len(letters))
+	}
+}
+
+func TestPipelineRetriesRejectedWebhook(t *testing.T) {
+	type request struct {
+		signature, timestamp, delivery string
+		body                           []byte
+	}
+	requests := make(chan request, 10)
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{
+			signature: r.Header.Get(webhook.SignatureHeader),
+			timestamp: r.Header.Get(webhook.TimestampHeader),
+			delivery:  r.Header.Get(webhook.DeliveryHeader),
+			body:      body,
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	p := newPipeline(func(ctx context.Context, call int, req *service.ParseMenuRequest) (*entity.Menu, error) {
+		return parsedMenu(req), nil
+	})
+	p.webhooks = webhook.NewSender(testWebhookSecret, time.Second, true)
+	taskID := p.createTaskWithCallback(t, server.URL)
+	p.start(t)
+
+	waitFor(t, "two webhook deliveries", func() bool {
+		return len(p.task(t, taskID).WebhookDeliveries) == 2
+	})
+
+	deliveries := p.task(t, taskID).WebhookDeliveries
+	rejected, accepted := deliveries[0], deliveries[1]
+	if rejected.StatusCode != http.StatusInternalServerError || rejected.Error != "callback URL responded with 500" || rejected.Attempt != 1 {
+		t.Errorf("first delivery = %+v, want attempt 1 rejected with 500", rejected)
+	}
+	if accepted.StatusCode != http.StatusOK || accepted.Error != "" || accepted.Attempt != 2 {
+		t.Errorf("second delivery = %+v, want attempt 2 accepted with 200", accepted)
+	}
+	if rejected.DeliveryID != accepted.DeliveryID {
+		t.Errorf("delivery IDs %q and %q differ between attempts", rejected.DeliveryID, accepted.DeliveryID)
+	}
+
+	for i := 0; i < 2; i++ {
+		req := <-requests
+		want := "sha256=" + webhook.Sign([]byte(testWebhookSecret), req.timestamp, req.body)
+		if req.signature != want {
+			t.Errorf("request %d: signature %q does not match the body", i+1, req.signature)
+		}
+		if req.delivery != accepted.DeliveryID {
+			t.Errorf("request %d: delivery header %q, want %q", i+1, req.delivery, accepted.DeliveryID)
+		}
+		var payload entity.TaskWebhookPayload
+		if err := json.Unmarshal(req.body, &payload); err != nil || payload.TaskID != taskID || payload.Status != entity.TaskStatusCompleted {
+			t.Errorf("request %d: payload %s, want the completed task %s", i+1, req.body, taskID)
+		}
+	}
+	settle()
+	if got := calls.Load(); got != 2 {
+		t.Errorf("callback URL called %d times, want 2", got)
+	}
+}
+
+func TestPipelineFailsWebhookToPrivateAddress(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	p := newPipeline(func(ctx context.Context, call int, req *service.ParseMenuRequest) (*entity.Menu, error) {
+		return parsedMenu(req), nil
+	})
+	p.webhooks = webhook.NewSender(testWebhookSecret, time.Second, false)
+	taskID := p.createTaskWithCallback(t, server.URL)
+	p.start(t)
+
+	waitFor(t, "webhook delivery", func() bool {
+		return len(p.task(t, taskID).WebhookDeliveries) > 0
+	})
+	settle()
+
+	deliveries := p.task(t, taskID).WebhookDeliveries
+	if len(deliveries) != 1 || deliveries[0].Error != "callback URL does not resolve to a public address" {
+		t.Errorf("deliveries = %+v, want one refused attempt", deliveries)
+	}
+	if got := calls.Load(); got != 0 {
+		t.Errorf("callback URL called %d times, want none", got)
+	}
+	if failed := p.outbox.withStatus(entity.OutboxStatusFailed); len(failed) != 1 || failed[0].Type != entity.OutboxMessageTaskWebhook {
+		t.Errorf("failed outbox messages = %+v, want the webhook", failed)
+	}
+}
+
+func TestPipelineRequeuesTransientProductEventFailure(t *testing.T) {
+	p := newPipeline(nil)
+	p.seedMenu(t, "p1")
+	p.menus.statusFailures = 1
+	p.menus.statusErr = errDatabaseUnavailable
+
+	err := p.productUseCase.UpdateProductStatus(context.Background(), testRestaurant, "p1",
+		string(entity.ProductStatusNotAvailable), "stop-list", "tester")
+	if err != nil {
+		t.Fatalf("update product status: %v", err)
+	}
+	p.start(t)
+
+	waitFor(t, "status event to be applied", func() bool {
+		return len(p.audits.byProduct("p1")) > 0
+	})
+	settle()
+
+	status, _ := p.menus.GetProductStatus(context.Background(), testRestaurant, "p1")
+	if status != string(entity.ProductStatusNotAvailable) {
+		t.Errorf("product status = %q, want not_available", status)
+	}
+	if audits := p.audits.byProduct("p1"); len(audits) != 1 {
+		t.Errorf("got %d audit records, want 1", len(audits))
+	}
+	p.menus.mu.Lock()
+	remaining := p.menus.statusFailures
+	p.menus.mu.Unlock()
+	if remaining != 0 {
+		t.Error("the failing update was never attempted")
+	}
+	if letters := p.deadLetters(t); len(letters) != 0 {
+		t.Errorf("dead-letter queue holds %d messages, want none", len(letters))
+	}
+}
+
+func TestPipelineDeadLettersEventOfRemovedProduct(t *testing.T) {
+	p := newPipeline(nil)
+	p.seedMenu(t, "p1", "p2")
+
+	err := p.productUseCase.UpdateProductStatus(context.Background(), testRestaurant, "p2",
+		string(entity.ProductStatusNotAvailable), "stop-list", "tester")
+	if err != nil {
+		t.Fatalf("update product status: %v", err)
+	}
+	// A new menu without the product becomes active before the event is processed
+	p.menus.removeProduct(testRestaurant, "p2")
+	p.start(t)
+
+	waitFor(t, "status event to be dead-lettered", func() bool {
+		return len(p.deadLetters(t)) == 1
+	})
+
+	if letter := p.deadLetters(t)[0]; letter.SourceQueue != "product-status" {
+		t.Errorf("dead letter from %q, want product-status", letter.SourceQueue)
+	}
+	if audits := p.audits.byProduct("p2"); len(audits) != 0 {
+		t.Errorf("got %d audit records for the removed product, want none", len(audits))
+	}
+}
@@ -0,0 +1,471 @@
+package queue
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+	"menu-parser/internal/domain/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// In-memory repositories for running the parsing pipeline without MongoDB.
+// Each embeds its interface, so methods the pipeline does not use panic.
+
+type fakeTaskRepo struct {
+	repository.TaskRepository
+	mu    sync.Mutex
+	tasks map[string]*entity.ParsingTask
+	// completeFailures makes that many updates to completed fail
+	completeFailures int
+}
+
+func newFakeTaskRepo() *fakeTaskRepo {
+	return &fakeTaskRepo{tasks: map[string]*entity.ParsingTask{}}
+}
+
+func (r *fakeTaskRepo) Create(ctx context.Context, task *entity.ParsingTask) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *task
+	r.tasks[task.ID] = &stored
+	return nil
+}
+
+func (r *fakeTaskRepo) GetByID(ctx context.Context, taskID string) (*entity.ParsingTask, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return nil, repository.ErrTaskNotFound
+	}
+	found := *task
+	return &found, nil
+}
+
+// update applies fn to a task that is not cancelled and reports whether it did
+func (r *fakeTaskRepo) update(taskID string, fn func(task *entity.ParsingTask)) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	task, ok := r.tasks[taskID]
+	if !ok || task.Status == entity.TaskStatusCancelled {
+		return false
+	}
+	fn(task)
+	task.UpdatedAt = time.Now()
+	return true
+}
+
+func (r *fakeTaskRepo) UpdateStatus(ctx context.Context, taskID string, status entity.ParsingTaskStatus, menuID *primitive.ObjectID, errorMsg string) (bool, error) {
+	if status == entity.TaskStatusCompleted && r.failCompletion() {
+		return false, errDatabaseUnavailable
+	}
+	return r.update(taskID, func(task *entity.ParsingTask) {
+		task.Status = status
+		if menuID != nil {
+			task.MenuID = menuID
+		}
+		if errorMsg != "" {
+			task.ErrorMessage = errorMsg
+		}
+	}), nil
+}
+
+func (r *fakeTaskRepo) failCompletion() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.completeFailures == 0 {
+		return false
+	}
+	r.completeFailures--
+	return true
+}
+
+func (r *fakeTaskRepo) MarkFailed(ctx context.Context, taskID, errorCode, errorMsg string) (bool, error) {
+	return r.update(taskID, func(task *entity.ParsingTask) {
+		task.Status = entity.TaskStatusFailed
+		task.ErrorCode = errorCode
+		task.ErrorMessage = errorMsg
+	}), nil
+}
+
+func (r *fakeTaskRepo) RecordError(ctx context.Context, taskID, errorCode, errorMsg string) error {
+	r.update(taskID, func(task *entity.ParsingTask) {
+		task.ErrorCode = errorCode
+		task.ErrorMessage = errorMsg
+	})
+	return nil
+}
+
+func (r *fakeTaskRepo) IncrementRetryCount(ctx context.Context, taskID string) error {
+	r.update(taskID, func(task *entity.ParsingTask) {
+		task.RetryCount++
+	})
+	return nil
+}
+
+func (r *fakeTaskRepo) Cancel(ctx context.Context, taskID, requestedBy string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	task, ok := r.tasks[taskID]
+	if !ok || (task.Status != entity.TaskStatusQueued && task.Status != entity.TaskStatusProcessing) {
+		return false, nil
+	}
+	task.Status = entity.TaskStatusCancelled
+	task.CancelledBy = requestedBy
+	return true, nil
+}
+
+func (r *fakeTaskRepo) RecordWebhookDelivery(ctx context.Context, taskID string, delivery *entity.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if task, ok := r.tasks[taskID]; ok {
+		task.WebhookDeliveries = append(task.WebhookDeliveries, *delivery)
+	}
+	return nil
+}
+
+func (r *fakeTaskRepo) SaveDiff(ctx context.Context, taskID string, diff *entity.MenuDiff) error {
+	return nil
+}
+
+func (r *fakeTaskRepo) ClaimForProcessing(ctx context.Context, taskID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	task, ok := r.tasks[taskID]
+	if !ok || task.Status != entity.TaskStatusQueued {
+		return false, nil
+	}
+	task.Status = entity.TaskStatusProcessing
+	return true, nil
+}
+
+type fakeMenuRepo struct {
+	repository.MenuRepository
+	mu     sync.Mutex
+	menus  map[primitive.ObjectID]*entity.Menu
+	active map[string]primitive.ObjectID
+	// statusFailures makes that many UpdateProductStatus calls fail with this error
+	statusFailures int
+	statusErr      error
+	// onActivate runs after a menu was activated
+	onActivate func()
+}
+
+func newFakeMenuRepo() *fakeMenuRepo {
+	return &fakeMenuRepo{
+		menus:  map[primitive.ObjectID]*entity.Menu{},
+		active: map[string]primitive.ObjectID{},
+	}
+}
+
+func (r *fakeMenuRepo) Create(ctx context.Context, menu *entity.Menu) (*entity.Menu, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	menu.ID = primitive.NewObjectID()
+	menu.Version = len(r.menus) + 1
+	stored := *menu
+	stored.Products = append([]entity.Product(nil), menu.Products...)
+	r.menus[menu.ID] = &stored
+	return menu, nil
+}
+
+func (r *fakeMenuRepo) GetByID(ctx context.Context, menuID string) (*entity.Menu, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, _ := primitive.ObjectIDFromHex(menuID)
+	menu, ok := r.menus[id]
+	if !ok {
+		return nil, repository.ErrMenuNotFound
+	}
+	found := *menu
+	found.Products = append([]entity.Product(nil), menu.Products...)
+	return &found, nil
+}
+
+func (r *fakeMenuRepo) Update(ctx context.Context, menu *entity.Menu) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.menus[menu.ID]
+	if !ok {
+		return repository.ErrMenuNotFound
+	}
+	if stored.Revision != menu.Revision {
+		return repository.ErrMenuModified
+	}
+	menu.Revision++
+	updated := *menu
+	updated.Products = append([]entity.Product(nil), menu.Products...)
+	r.menus[menu.ID] = &updated
+	return nil
+}
+
+func (r *fakeMenuRepo) Activate(ctx context.Context, restaurantID, menuID string) (*entity.Menu, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, _ := primitive.ObjectIDFromHex(menuID)
+	menu, ok := r.menus[id]
+	if !ok {
+		return nil, repository.ErrMenuNotFound
+	}
+	r.active[restaurantID] = id
+	if r.onActivate != nil {
+		r.onActivate()
+	}
+	found := *menu
+	return &found, nil
+}
+
+func (r *fakeMenuRepo) GetActiveByRestaurant(ctx context.Context, restaurantID string) (*entity.Menu, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, ok := r.active[restaurantID]
+	if !ok {
+		return nil, nil
+	}
+	found := *r.menus[id]
+	found.Products = append([]entity.Product(nil), r.menus[id].Products...)
+	return &found, nil
+}
+
+// activeProduct returns the product of the restaurant's active menu; callers hold mu
+func (r *fakeMenuRepo) activeProduct(restaurantID, productID string) (*entity.Menu, *entity.Product) {
+	menu, ok := r.menus[r.active[restaurantID]]
+	if !ok {
+		return nil, nil
+	}
+	for i := range menu.Products {
+		if menu.Products[i].ExtID == productID {
+			return menu, &menu.Products[i]
+		}
+	}
+	return menu, nil
+}
+
+func (r *fakeMenuRepo) GetProductStatus(ctx context.Context, restaurantID, productID string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, product := r.activeProduct(restaurantID, productID)
+	if product == nil {
+		return "", repository.ErrProductNotFound
+	}
+	return product.Status, nil
+}
+
+func (r *fakeMenuRepo) UpdateProductStatus(ctx context.Context, restaurantID, productID, newStatus string) (primitive.ObjectID, *entity.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.statusFailures > 0 {
+		r.statusFailures--
+		return primitive.NilObjectID, nil, r.statusErr
+	}
+	menu, product := r.activeProduct(restaurantID, productID)
+	if product == nil {
+		return primitive.NilObjectID, nil, repository.ErrProductNotFound
+	}
+	previous := *product
+	product.Status = newStatus
+	return menu.ID, &previous, nil
+}
+
+// removeProduct drops a product from the restaurant's active menu
+func (r *fakeMenuRepo) removeProduct(restaurantID, productID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	menu, _ := r.activeProduct(restaurantID, productID)
+	kept := menu.Products[:0]
+	for _, product := range menu.Products {
+		if product.ExtID != productID {
+			kept = append(kept, product)
+		}
+	}
+	menu.Products = kept
+}
+
+type fakeMappingRepo struct {
+	repository.ColumnMappingRepository
+}
+
+func (fakeMappingRepo) GetByName(ctx context.Context, restaurantID, name string) (*entity.ColumnMappingProfile, error) {
+	return nil, repository.ErrColumnMappingProfileNotFound
+}
+
+type fakeAuditRepo struct {
+	repository.AuditRepository
+	mu     sync.Mutex
+	audits []entity.ProductStatusAudit
+}
+
+// exists applies the unique index on correlation ID, product and event type; callers hold mu
+func (r *fakeAuditRepo) exists(audit entity.ProductStatusAudit) bool {
+	for _, stored := range r.audits {
+		if audit.CorrelationID != "" && stored.CorrelationID == audit.CorrelationID &&
+			stored.ProductID == audit.ProductID && stored.EventType == audit.EventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *fakeAuditRepo) Create(ctx context.Context, audit *entity.ProductStatusAudit) error {
+	return r.CreateMany(ctx, []entity.ProductStatusAudit{*audit})
+}
+
+func (r *fakeAuditRepo) CreateMany(ctx context.Context, audits []entity.ProductStatusAudit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := len(r.audits)
+	for _, audit := range audits {
+		if r.exists(audit) {
+			r.audits = r.audits[:stored]
+			return repository.ErrAuditRecordExists
+		}
+		r.audits = append(r.audits, audit)
+	}
+	return nil
+}
+
+func (r *fakeAuditRepo) ExistsByCorrelationID(ctx context.Context, correlationID, productID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, audit := range r.audits {
+		if audit.CorrelationID == correlationID && audit.ProductID == productID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeAuditRepo) byProduct(productID string) []entity.ProductStatusAudit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []entity.ProductStatusAudit
+	for _, audit := range r.audits {
+		if audit.ProductID == productID {
+			found = append(found, audit)
+		}
+	}
+	return found
+}
+
+type fakeOutboxRepo struct {
+	mu       sync.Mutex
+	messages map[primitive.ObjectID]*entity.OutboxMessage
+}
+
+func newFakeOutboxRepo() *fakeOutboxRepo {
+	return &fakeOutboxRepo{messages: map[primitive.ObjectID]*entity.OutboxMessage{}}
+}
+
+func (r *fakeOutboxRepo) Create(ctx context.Context, message *entity.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	message.ID = primitive.NewObjectID()
+	stored := *message
+	r.messages[message.ID] = &stored
+	return nil
+}
+
+func (r *fakeOutboxRepo) ClaimNext(ctx context.Context, lease time.Duration) (*entity.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var due []*entity.OutboxMessage
+	for _, message := range r.messages {
+		if message.Status == entity.OutboxStatusPending && !message.AvailableAt.After(now) {
+			due = append(due, message)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID.Hex() < due[j].ID.Hex() })
+
+	message := due[0]
+	message.AvailableAt = now.Add(lease)
+	message.Attempts++
+	claimed := *message
+	return &claimed, nil
+}
+
+func (r *fakeOutboxRepo) setStatus(id primitive.ObjectID, status entity.OutboxStatus, lastError string, availableAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	message := r.messages[id]
+	message.Status = status
+	message.LastError = lastError
+	if !availableAt.IsZero() {
+		message.AvailableAt = availableAt
+	}
+}
+
+func (r *fakeOutboxRepo) MarkPublished(ctx context.Context, id primitive.ObjectID) error {
+	r.setStatus(id, entity.OutboxStatusPublished, "", time.Time{})
+	return nil
+}
+
+func (r *fakeOutboxRepo) ScheduleRetry(ctx context.Context, id primitive.ObjectID, lastError string, availableAt time.Time) error {
+	r.setStatus(id, entity.OutboxStatusPending, lastError, availableAt)
+	return nil
+}
+
+func (r *fakeOutboxRepo) MarkFailed(ctx context.Context, id primitive.ObjectID, lastError string) error {
+	r.setStatus(id, entity.OutboxStatusFailed, lastError, time.Time{})
+	return nil
+}
+
+func (r *fakeOutboxRepo) withType(messageType entity.OutboxMessageType) []entity.OutboxMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []entity.OutboxMessage
+	for _, message := range r.messages {
+		if message.Type == messageType {
+			found = append(found, *message)
+		}
+	}
+	return found
+}
+
+func (r *fakeOutboxRepo) withStatus(status entity.OutboxStatus) []entity.OutboxMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []entity.OutboxMessage
+	for _, message := range r.messages {
+		if message.Status == status {
+			found = append(found, *message)
+		}
+	}
+	return found
+}
+
+type fakeTransactor struct{}
+
+func (fakeTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// fakeParser answers parse requests with parse and counts them
+type fakeParser struct {
+	mu    sync.Mutex
+	calls int
+	parse func(ctx context.Context, call int, req *service.ParseMenuRequest) (*entity.Menu, error)
+}
+
+func (p *fakeParser) ParseMenu(ctx context.Context, req *service.ParseMenuRequest) (*entity.Menu, error) {
+	p.mu.Lock()
+	p.calls++
+	call := p.calls
+	p.mu.Unlock()
+	return p.parse(ctx, call, req)
+}
+
+func (p *fakeParser) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
@@ -0,0 +1,55 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/service"
+)
+
+func statusEventMessage(t *testing.T, event entity.ProductStatusChangeEvent) service.Message {
+	t.Helper()
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	return service.Message{Body: body}
+}
+
+func TestProductShardIsStablePerProduct(t *testing.T) {
+	const shards = 8
+
+	used := map[int]bool{}
+	for i := 0; i < 100; i++ {
+		restaurantID, productID := fmt.Sprintf("rest-%d", i%5), fmt.Sprintf("p%d", i)
+		shard := productShard(statusEventMessage(t, entity.ProductStatusChangeEvent{
+			EventID: "event-1", RestaurantID: restaurantID, ProductID: productID, NewStatus: "available",
+		}), shards)
+		if shard < 0 || shard >= shards {
+			t.Fatalf("shard %d of %s/%s is out of range", shard, restaurantID, productID)
+		}
+		used[shard] = true
+
+		// Other events of the same product go to the same shard
+		again := productShard(statusEventMessage(t, entity.ProductStatusChangeEvent{
+			EventID: "event-2", RestaurantID: restaurantID, ProductID: productID, NewStatus: "not_available", UserID: "alice",
+		}), shards)
+		if again != shard {
+			t.Errorf("events of %s/%s went to shards %d and %d", restaurantID, productID, shard, again)
+		}
+	}
+	if len(used) < shards/2 {
+		t.Errorf("100 products used only %d of %d shards", len(used), shards)
+	}
+}
+
+func TestProductShardOfUndecodableEvent(t *testing.T) {
+	if shard := productShard(service.Message{Body: []byte("not json")}, 8); shard != 0 {
+		t.Errorf("shard of an undecodable event = %d, want 0", shard)
+	}
+	if shard := productShard(statusEventMessage(t, entity.ProductStatusChangeEvent{RestaurantID: "r", ProductID: "p"}), 1); shard != 0 {
+		t.Errorf("shard with a single worker = %d, want 0", shard)
+	}
+}
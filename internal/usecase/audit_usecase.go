@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"menu-parser/internal/domain/apperror"
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+// ErrInvalidAuditQuery is wrapped by validation errors of audit queries
+var ErrInvalidAuditQuery = apperror.New(apperror.KindInvalidArgument, "invalid_audit_query", "invalid audit query")
+
+// AuditUseCase exposes the product status history
+type AuditUseCase struct {
+	auditRepo repository.AuditRepository
+	menuRepo  repository.MenuRepository
+}
+
+// NewAuditUseCase creates a new AuditUseCase
+func NewAuditUseCase(auditRepo repository.AuditRepository, menuRepo repository.MenuRepository) *AuditUseCase {
+	return &AuditUseCase{
+		auditRepo: auditRepo,
+		menuRepo:  menuRepo,
+	}
+}
+
+// AuditBackfillResult counts what a backfill run did
+type AuditBackfillResult struct {
+	Scanned   int
+	Updated   int
+	Ambiguous int
+	Unmatched int
+}
+
+// ListAudit returns a page of audit records matching the filter, newest first,
+// and the cursor of the next page
+func (uc *AuditUseCase) ListAudit(ctx context.Context, filter repository.AuditFilter) ([]entity.ProductStatusAudit, string, error) {
+	if filter.RestaurantID == "" {
+		return nil, "", fmt.Errorf("%w: restaurant_id is required", ErrInvalidAuditQuery)
+	}
+	if filter.Cursor != "" && !primitive.IsValidObjectID(filter.Cursor) {
+		return nil, "", fmt.Errorf("%w: malformed cursor", ErrInvalidAuditQuery)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return nil, "", fmt.Errorf("%w: from must not be after to", ErrInvalidAuditQuery)
+	}
+
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = defaultAuditPageSize
+	case filter.Limit > maxAuditPageSize:
+		filter.Limit = maxAuditPageSize
+	}
+
+	audits, nextCursor, err := uc.auditRepo.List(ctx, filter)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list audit records: %w", err)
+	}
+
+	return audits, nextCursor, nil
+}
+
+// BackfillContext fills in the restaurant of audit records written before it
+// was recorded. The restaurant is inferred from the menus containing the
+// product and only when exactly one restaurant has it; the menu and product
+// name come from that restaurant's latest menu created before the record.
+// With dryRun nothing is written.
+func (uc *AuditUseCase) BackfillContext(ctx context.Context, batchSize int, dryRun bool) (*AuditBackfillResult, error) {
+	if batchSize <= 0 {
+		batchSize = defaultAuditPageSize
+	}
+
+	result := &AuditBackfillResult{}
+	menusByProduct := make(map[string][]entity.Menu)
+	afterID := ""
+
+	for {
+		audits, err := uc.auditRepo.ListWithoutRestaurant(ctx, afterID, batchSize)
+		if err != nil {
+			return result, err
+		}
+		if len(audits) == 0 {
+			return result, nil
+		}
+
+		for i := range audits {
+			audit := &audits[i]
+			afterID = audit.ID.Hex()
+			result.Scanned++
+
+			menus, ok := menusByProduct[audit.ProductID]
+			if !ok {
+				menus, err = uc.menuRepo.FindByProduct(ctx, audit.ProductID)
+				if err != nil {
+					return result, err
+				}
+				menusByProduct[audit.ProductID] = menus
+			}
+
+			menu, restaurants := menuForAudit(menus, audit.Timestamp)
+			switch {
+			case restaurants == 0:
+				result.Unmatched++
+				continue
+			case restaurants > 1:
+				result.Ambiguous++
+				continue
+			}
+
+			audit.RestaurantID = menu.RestaurantID
+			audit.MenuID = menu.ID
+			if audit.ProductName == "" && len(menu.Products) > 0 {
+				audit.ProductName = menu.Products[0].Name
+			}
+
+			if !dryRun {
+				if err := uc.auditRepo.UpdateContext(ctx, audit); err != nil {
+					return result, err
+				}
+			}
+			result.Updated++
+		}
+	}
+}
+
+// menuForAudit picks the menu an audit record most likely refers to: the latest
+// one created at or before the record, or the earliest one otherwise. It also
+// reports how many distinct restaurants the menus belong to.
+func menuForAudit(menus []entity.Menu, at time.Time) (*entity.Menu, int) {
+	restaurants := make(map[string]bool)
+	var picked *entity.Menu
+	for i := range menus {
+		restaurants[menus[i].RestaurantID] = true
+		if picked == nil || !menus[i].CreatedAt.After(at) {
+			picked = &menus[i]
+		}
+	}
+	return picked, len(restaurants)
+}
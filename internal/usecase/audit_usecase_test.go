@@ -0,0 +1,226 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+	mongorepo "menu-parser/internal/repository"
+)
+
+type filterRecordingAuditRepo struct {
+	repository.AuditRepository
+	filter *repository.AuditFilter
+}
+
+func (r *filterRecordingAuditRepo) List(ctx context.Context, filter repository.AuditFilter) ([]entity.ProductStatusAudit, string, error) {
+	r.filter = &filter
+	return nil, "", nil
+}
+
+func TestListAuditValidatesQuery(t *testing.T) {
+	now := time.Now()
+	cursor := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name      string
+		filter    repository.AuditFilter
+		wantLimit int
+	}{
+		{"default page size", repository.AuditFilter{RestaurantID: "rest-1"}, defaultAuditPageSize},
+		{"page size", repository.AuditFilter{RestaurantID: "rest-1", Limit: 10}, 10},
+		{"page size capped", repository.AuditFilter{RestaurantID: "rest-1", Limit: 10000}, maxAuditPageSize},
+		{"cursor", repository.AuditFilter{RestaurantID: "rest-1", Cursor: cursor}, defaultAuditPageSize},
+		{"time range", repository.AuditFilter{RestaurantID: "rest-1", From: now.Add(-time.Hour), To: now}, defaultAuditPageSize},
+		{"without restaurant", repository.AuditFilter{}, 0},
+		{"malformed cursor", repository.AuditFilter{RestaurantID: "rest-1", Cursor: "page-2"}, 0},
+		{"cursor of another ID type", repository.AuditFilter{RestaurantID: "rest-1", Cursor: "550e8400-e29b-41d4-a716-446655440000"}, 0},
+		{"from after to", repository.AuditFilter{RestaurantID: "rest-1", From: now, To: now.Add(-time.Hour)}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &filterRecordingAuditRepo{}
+			_, _, err := NewAuditUseCase(repo, nil).ListAudit(context.Background(), tt.filter)
+
+			if tt.wantLimit == 0 {
+				if !errors.Is(err, ErrInvalidAuditQuery) || repo.filter != nil {
+					t.Errorf("error = %v, want %v without a query", err, ErrInvalidAuditQuery)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			if repo.filter.Limit != tt.wantLimit || repo.filter.Cursor != tt.filter.Cursor {
+				t.Errorf("query limit %d, cursor %q; want %d, %q", repo.filter.Limit, repo.filter.Cursor, tt.wantLimit, tt.filter.Cursor)
+			}
+		})
+	}
+}
+
+func TestListAuditPagesMongo(t *testing.T) {
+	db := newTestMongoDB(t)
+	ctx := context.Background()
+
+	auditRepo := mongorepo.NewAuditRepository(db)
+	uc := NewAuditUseCase(auditRepo, mongorepo.NewMenuRepository(db))
+
+	const records = 7
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < records; i++ {
+		if err := auditRepo.Create(ctx, &entity.ProductStatusAudit{
+			RestaurantID: "rest-1",
+			ProductID:    fmt.Sprintf("p%d", i),
+			NewStatus:    "not_available",
+			Timestamp:    start.Add(time.Duration(i) * time.Minute),
+		}); err != nil {
+			t.Fatalf("create audit record: %v", err)
+		}
+	}
+	// Another restaurant's record never shows up
+	auditRepo.Create(ctx, &entity.ProductStatusAudit{RestaurantID: "rest-2", ProductID: "p0", Timestamp: start})
+
+	var seen []string
+	cursor := ""
+	for page := 0; ; page++ {
+		audits, next, err := uc.ListAudit(ctx, repository.AuditFilter{RestaurantID: "rest-1", Cursor: cursor, Limit: 3})
+		if err != nil {
+			t.Fatalf("list page %d: %v", page, err)
+		}
+		if len(audits) > 3 {
+			t.Fatalf("page %d holds %d records, want at most 3", page, len(audits))
+		}
+		for _, audit := range audits {
+			seen = append(seen, audit.ProductID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	// Newest first, every record exactly once
+	want := []string{"p6", "p5", "p4", "p3", "p2", "p1", "p0"}
+	if fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Errorf("paged records = %v, want %v", seen, want)
+	}
+}
+
+func TestMenuForAudit(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	menu := func(restaurantID string, createdAt time.Time) entity.Menu {
+		return entity.Menu{ID: primitive.NewObjectID(), RestaurantID: restaurantID, CreatedAt: createdAt}
+	}
+	older := menu("rest-1", at.Add(-48*time.Hour))
+	old := menu("rest-1", at.Add(-time.Hour))
+	same := menu("rest-1", at)
+	newer := menu("rest-1", at.Add(time.Hour))
+	newest := menu("rest-1", at.Add(48*time.Hour))
+	otherRestaurant := menu("rest-2", at.Add(-time.Hour))
+
+	tests := []struct {
+		name        string
+		menus       []entity.Menu
+		want        *entity.Menu
+		restaurants int
+	}{
+		{"no menus", nil, nil, 0},
+		{"latest menu before the record", []entity.Menu{older, old, newer}, &old, 1},
+		{"menu created at the record", []entity.Menu{old, same, newer}, &same, 1},
+		{"only later menus", []entity.Menu{newer, newest}, &newer, 1},
+		{"several restaurants", []entity.Menu{old, otherRestaurant}, &otherRestaurant, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picked, restaurants := menuForAudit(tt.menus, at)
+			if restaurants != tt.restaurants {
+				t.Errorf("restaurants = %d, want %d", restaurants, tt.restaurants)
+			}
+			if (picked == nil) != (tt.want == nil) || (picked != nil && picked.ID != tt.want.ID) {
+				t.Errorf("picked menu = %+v, want %+v", picked, tt.want)
+			}
+		})
+	}
+}
+
+// backfillAuditRepo pages through records without a restaurant and keeps the updates
+type backfillAuditRepo struct {
+	repository.AuditRepository
+	audits  []entity.ProductStatusAudit
+	updated []entity.ProductStatusAudit
+}
+
+func (r *backfillAuditRepo) ListWithoutRestaurant(ctx context.Context, afterID string, limit int) ([]entity.ProductStatusAudit, error) {
+	var page []entity.ProductStatusAudit
+	for _, audit := range r.audits {
+		if audit.ID.Hex() > afterID && len(page) < limit {
+			page = append(page, audit)
+		}
+	}
+	return page, nil
+}
+
+func (r *backfillAuditRepo) UpdateContext(ctx context.Context, audit *entity.ProductStatusAudit) error {
+	r.updated = append(r.updated, *audit)
+	return nil
+}
+
+type productMenusRepo struct {
+	repository.MenuRepository
+	menus   map[string][]entity.Menu
+	lookups int
+}
+
+func (r *productMenusRepo) FindByProduct(ctx context.Context, productID string) ([]entity.Menu, error) {
+	r.lookups++
+	return r.menus[productID], nil
+}
+
+func TestBackfillContext(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	menu := entity.Menu{
+		ID:           primitive.NewObjectID(),
+		RestaurantID: "rest-1",
+		CreatedAt:    at.Add(-time.Hour),
+		Products:     []entity.Product{{ExtID: "p1", Name: "Борщ"}},
+	}
+	menus := &productMenusRepo{menus: map[string][]entity.Menu{
+		"p1":     {menu},
+		"shared": {{RestaurantID: "rest-1"}, {RestaurantID: "rest-2"}},
+	}}
+
+	audits := &backfillAuditRepo{}
+	for _, productID := range []string{"p1", "shared", "gone", "p1", "p1"} {
+		audits.audits = append(audits.audits, entity.ProductStatusAudit{ID: primitive.NewObjectID(), ProductID: productID, Timestamp: at})
+	}
+	uc := NewAuditUseCase(audits, menus)
+
+	result, err := uc.BackfillContext(context.Background(), 2, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	want := AuditBackfillResult{Scanned: 5, Updated: 3, Ambiguous: 1, Unmatched: 1}
+	if *result != want || len(audits.updated) != 0 {
+		t.Errorf("dry run = %+v with %d writes, want %+v without writes", *result, len(audits.updated), want)
+	}
+
+	if _, err := uc.BackfillContext(context.Background(), 2, false); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if len(audits.updated) != 3 {
+		t.Fatalf("updated %d records, want 3", len(audits.updated))
+	}
+	if updated := audits.updated[0]; updated.RestaurantID != "rest-1" || updated.MenuID != menu.ID || updated.ProductName != "Борщ" {
+		t.Errorf("updated record = %+v, want rest-1, its menu and the product name", updated)
+	}
+	// One lookup per product and run
+	if menus.lookups != 6 {
+		t.Errorf("menu lookups = %d, want 6", menus.lookups)
+	}
+}
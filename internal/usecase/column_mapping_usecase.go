@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+)
+
+// ColumnMappingUseCase manages per-restaurant column mapping profiles
+type ColumnMappingUseCase struct {
+	mappingRepo repository.ColumnMappingRepository
+}
+
+// NewColumnMappingUseCase creates a new ColumnMappingUseCase
+func NewColumnMappingUseCase(mappingRepo repository.ColumnMappingRepository) *ColumnMappingUseCase {
+	return &ColumnMappingUseCase{
+		mappingRepo: mappingRepo,
+	}
+}
+
+// SaveProfile creates or replaces a named profile for a restaurant
+func (uc *ColumnMappingUseCase) SaveProfile(ctx context.Context, restaurantID, name string, mapping entity.ColumnMapping) (*entity.ColumnMappingProfile, error) {
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+
+	profile, err := uc.mappingRepo.Upsert(ctx, &entity.ColumnMappingProfile{
+		RestaurantID: restaurantID,
+		Name:         name,
+		Mapping:      mapping,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// GetProfile retrieves a named profile for a restaurant
+func (uc *ColumnMappingUseCase) GetProfile(ctx context.Context, restaurantID, name string) (*entity.ColumnMappingProfile, error) {
+	return uc.mappingRepo.GetByName(ctx, restaurantID, name)
+}
+
+// ListProfiles lists all profiles stored for a restaurant
+func (uc *ColumnMappingUseCase) ListProfiles(ctx context.Context, restaurantID string) ([]entity.ColumnMappingProfile, error) {
+	return uc.mappingRepo.ListByRestaurant(ctx, restaurantID)
+}
+
+// DeleteProfile removes a named profile for a restaurant
+func (uc *ColumnMappingUseCase) DeleteProfile(ctx context.Context, restaurantID, name string) error {
+	return uc.mappingRepo.Delete(ctx, restaurantID, name)
+}
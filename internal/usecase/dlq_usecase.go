@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"menu-parser/internal/domain/apperror"
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+	"menu-parser/internal/domain/service"
+)
+
+const (
+	defaultDeadLetterScan = 100
+	maxDeadLetterScan     = 1000
+)
+
+// ErrInvalidDeadLetterRequest is wrapped by validation errors of replay and purge requests
+var ErrInvalidDeadLetterRequest = apperror.New(apperror.KindInvalidArgument, "invalid_dlq_request", "invalid dead-letter request")
+
+// DLQUseCase inspects the dead-letter queue and replays or purges its messages
+type DLQUseCase struct {
+	deadLetterQueue service.DeadLetterQueue
+	taskRepo        repository.TaskRepository
+	dlqAuditRepo    repository.DeadLetterAuditRepository
+}
+
+// NewDLQUseCase creates a new DLQUseCase
+func NewDLQUseCase(deadLetterQueue service.DeadLetterQueue, taskRepo repository.TaskRepository, dlqAuditRepo repository.DeadLetterAuditRepository) *DLQUseCase {
+	return &DLQUseCase{
+		deadLetterQueue: deadLetterQueue,
+		taskRepo:        taskRepo,
+		dlqAuditRepo:    dlqAuditRepo,
+	}
+}
+
+// List returns up to limit messages from the head of the dead-letter queue
+func (uc *DLQUseCase) List(ctx context.Context, limit int) ([]entity.DeadLetter, error) {
+	letters, err := uc.deadLetterQueue.ListDeadLetters(scanLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	return letters, nil
+}
+
+// Replay republishes the selected messages, or all scanned ones with all set,
+// to the queue they were dead-lettered from. Failed parsing tasks of replayed
+// messages are queued again with a fresh retry budget first, otherwise the
+// worker would fail them again straight away.
+func (uc *DLQUseCase) Replay(ctx context.Context, messageIDs []string, all bool, limit int, userID string) ([]entity.DeadLetter, error) {
+	messageIDs, err := uc.selectMessages(messageIDs, all, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	letters, err := uc.deadLetterQueue.ListDeadLetters(scanLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	selected := make(map[string]bool, len(messageIDs))
+	for _, id := range messageIDs {
+		selected[id] = true
+	}
+	for _, letter := range letters {
+		if !selected[letter.MessageID] || letter.TaskID == "" {
+			continue
+		}
+		if _, err := uc.taskRepo.RequeueFailed(ctx, letter.TaskID, userID); err != nil {
+			return nil, fmt.Errorf("failed to requeue task %s: %w", letter.TaskID, err)
+		}
+	}
+
+	replayed, err := uc.deadLetterQueue.ReplayDeadLetters(messageIDs, scanLimit(limit))
+	uc.record(ctx, entity.DeadLetterActionReplay, replayed, userID)
+	if err != nil {
+		return replayed, fmt.Errorf("failed to replay dead letters: %w", err)
+	}
+
+	return replayed, nil
+}
+
+// Purge drops the selected messages, or all scanned ones with all set
+func (uc *DLQUseCase) Purge(ctx context.Context, messageIDs []string, all bool, limit int, userID string) ([]entity.DeadLetter, error) {
+	messageIDs, err := uc.selectMessages(messageIDs, all, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	purged, err := uc.deadLetterQueue.PurgeDeadLetters(messageIDs, scanLimit(limit))
+	uc.record(ctx, entity.DeadLetterActionPurge, purged, userID)
+	if err != nil {
+		return purged, fmt.Errorf("failed to purge dead letters: %w", err)
+	}
+
+	return purged, nil
+}
+
+// selectMessages validates the selection and expands all into the IDs of the
+// scanned messages
+func (uc *DLQUseCase) selectMessages(messageIDs []string, all bool, limit int) ([]string, error) {
+	if all && len(messageIDs) > 0 {
+		return nil, fmt.Errorf("%w: message_ids and all are mutually exclusive", ErrInvalidDeadLetterRequest)
+	}
+	if !all && len(messageIDs) == 0 {
+		return nil, fmt.Errorf("%w: message_ids or all is required", ErrInvalidDeadLetterRequest)
+	}
+	if !all {
+		return messageIDs, nil
+	}
+
+	letters, err := uc.deadLetterQueue.ListDeadLetters(scanLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	ids := make([]string, 0, len(letters))
+	for _, letter := range letters {
+		ids = append(ids, letter.MessageID)
+	}
+	return ids, nil
+}
+
+// record writes the audit of messages already replayed or purged. The queue
+// operation cannot be undone, so a failure is only logged.
+func (uc *DLQUseCase) record(ctx context.Context, action entity.DeadLetterAction, letters []entity.DeadLetter, userID string) {
+	if len(letters) == 0 {
+		return
+	}
+
+	now := time.Now()
+	records := make([]entity.DeadLetterAudit, 0, len(letters))
+	for _, letter := range letters {
+		records = append(records, entity.DeadLetterAudit{
+			Action:      action,
+			MessageID:   letter.MessageID,
+			SourceQueue: letter.SourceQueue,
+			Reason:      letter.Reason,
+			TaskID:      letter.TaskID,
+			Body:        string(letter.Body),
+			UserID:      userID,
+			Timestamp:   now,
+		})
+	}
+
+	if err := uc.dlqAuditRepo.CreateMany(ctx, records); err != nil {
+		log.Printf("Failed to record %s of %d dead letters: %v", action, len(records), err)
+	}
+}
+
+func scanLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return defaultDeadLetterScan
+	case limit > maxDeadLetterScan:
+		return maxDeadLetterScan
+	}
+	return limit
+}
@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+	"menu-parser/pkg/config"
+	rabbitmqQueue "menu-parser/pkg/queue"
+)
+
+type requeueTaskRepo struct {
+	repository.TaskRepository
+	requeued []string
+}
+
+func (r *requeueTaskRepo) RequeueFailed(ctx context.Context, taskID, requestedBy string) (bool, error) {
+	r.requeued = append(r.requeued, taskID+" by "+requestedBy)
+	return true, nil
+}
+
+type recordingDLQAuditRepo struct {
+	records []entity.DeadLetterAudit
+}
+
+func (r *recordingDLQAuditRepo) CreateMany(ctx context.Context, records []entity.DeadLetterAudit) error {
+	r.records = append(r.records, records...)
+	return nil
+}
+
+// newTestDLQ returns a use case over an in-memory DLQ holding one rejected
+// parsing message per task and one rejected status event
+func newTestDLQ(t *testing.T, taskIDs ...string) (*DLQUseCase, *rabbitmqQueue.MemoryQueue, *requeueTaskRepo, *recordingDLQAuditRepo) {
+	t.Helper()
+	memory := rabbitmqQueue.NewMemoryQueue(&config.Config{
+		RabbitMQMenuParsingQueue:   "menu-parsing",
+		RabbitMQProductStatusQueue: "product-status",
+		RabbitMQDLQQueue:           "dlq",
+		RabbitMQRetryDelays:        []time.Duration{time.Hour},
+	})
+	t.Cleanup(func() { memory.Close() })
+
+	tasks, _ := memory.ConsumeMenuParsingTasks()
+	for _, taskID := range taskIDs {
+		memory.PublishMenuParsingTask(taskID)
+		(<-tasks).Nack(false)
+	}
+	events, _ := memory.ConsumeProductStatusEvents()
+	memory.PublishProductStatusEvent(&entity.ProductStatusChangeEvent{EventID: "event-1"})
+	(<-events).Nack(false)
+
+	taskRepo := &requeueTaskRepo{}
+	auditRepo := &recordingDLQAuditRepo{}
+	return NewDLQUseCase(memory, taskRepo, auditRepo), memory, taskRepo, auditRepo
+}
+
+func messageIDs(letters []entity.DeadLetter) []string {
+	ids := make([]string, 0, len(letters))
+	for _, letter := range letters {
+		ids = append(ids, letter.MessageID)
+	}
+	return ids
+}
+
+func TestDLQReplayRequeuesTasks(t *testing.T) {
+	uc, memory, taskRepo, auditRepo := newTestDLQ(t, "task-1", "task-2")
+	ctx := context.Background()
+
+	letters, err := uc.List(ctx, 0)
+	if err != nil || len(letters) != 3 {
+		t.Fatalf("list = %+v, %v; want three dead letters", letters, err)
+	}
+
+	// The second task and the status event
+	replayed, err := uc.Replay(ctx, []string{letters[1].MessageID, letters[2].MessageID}, false, 0, "alice")
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if !slices.Equal(messageIDs(replayed), []string{letters[1].MessageID, letters[2].MessageID}) {
+		t.Errorf("replayed = %v, want the second task and the event", messageIDs(replayed))
+	}
+	if !slices.Equal(taskRepo.requeued, []string{"task-2 by alice"}) {
+		t.Errorf("requeued tasks = %v, want task-2 by alice", taskRepo.requeued)
+	}
+	if len(auditRepo.records) != 2 || auditRepo.records[0].Action != entity.DeadLetterActionReplay ||
+		auditRepo.records[0].TaskID != "task-2" || auditRepo.records[0].SourceQueue != "menu-parsing" ||
+		auditRepo.records[1].SourceQueue != "product-status" || auditRepo.records[1].UserID != "alice" {
+		t.Errorf("audit records = %+v, want replays of task-2 and the event by alice", auditRepo.records)
+	}
+
+	remaining, _ := memory.ListDeadLetters(10)
+	if !slices.Equal(messageIDs(remaining), []string{letters[0].MessageID}) {
+		t.Errorf("dead letters left = %v, want the first task", messageIDs(remaining))
+	}
+}
+
+func TestDLQPurgeAll(t *testing.T) {
+	uc, memory, taskRepo, auditRepo := newTestDLQ(t, "task-1", "task-2")
+
+	purged, err := uc.Purge(context.Background(), nil, true, 2, "alice")
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if len(purged) != 2 || purged[0].TaskID != "task-1" || purged[1].TaskID != "task-2" {
+		t.Errorf("purged = %+v, want the two scanned tasks", purged)
+	}
+	if len(taskRepo.requeued) != 0 {
+		t.Errorf("requeued tasks = %v, want none", taskRepo.requeued)
+	}
+	if len(auditRepo.records) != 2 || auditRepo.records[0].Action != entity.DeadLetterActionPurge {
+		t.Errorf("audit records = %+v, want two purges", auditRepo.records)
+	}
+
+	remaining, _ := memory.ListDeadLetters(10)
+	if len(remaining) != 1 || remaining[0].SourceQueue != "product-status" {
+		t.Errorf("dead letters left = %+v, want the event beyond the scan limit", remaining)
+	}
+}
+
+func TestDLQRejectsInvalidSelection(t *testing.T) {
+	uc, memory, _, auditRepo := newTestDLQ(t, "task-1")
+	ctx := context.Background()
+
+	for name, run := range map[string]func() ([]entity.DeadLetter, error){
+		"replay nothing":     func() ([]entity.DeadLetter, error) { return uc.Replay(ctx, nil, false, 0, "alice") },
+		"replay IDs and all": func() ([]entity.DeadLetter, error) { return uc.Replay(ctx, []string{"id"}, true, 0, "alice") },
+		"purge nothing":      func() ([]entity.DeadLetter, error) { return uc.Purge(ctx, nil, false, 0, "alice") },
+		"purge IDs and all":  func() ([]entity.DeadLetter, error) { return uc.Purge(ctx, []string{"id"}, true, 0, "alice") },
+	} {
+		if _, err := run(); !errors.Is(err, ErrInvalidDeadLetterRequest) {
+			t.Errorf("%s: error = %v, want %v", name, err, ErrInvalidDeadLetterRequest)
+		}
+	}
+
+	if remaining, _ := memory.ListDeadLetters(10); len(remaining) != 2 || len(auditRepo.records) != 0 {
+		t.Errorf("%d dead letters left and %d audit records, want both letters kept and no audit", len(remaining), len(auditRepo.records))
+	}
+}
@@ -2,9 +2,14 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrServiceDegraded is wrapped by check errors of a service that is
+// temporarily unavailable but recovering on its own, such as a reconnecting queue
+var ErrServiceDegraded = errors.New("service degraded")
+
 // HealthCheckService defines the interface for health checks
 type HealthCheckService interface {
 	CheckDatabase(ctx context.Context) error
@@ -42,7 +47,9 @@ func (uc *HealthUseCase) Check(ctx context.Context) *HealthResponse {
 	}
 
 	// Check queue
-	if err := uc.healthService.CheckQueue(); err != nil {
+	if err := uc.healthService.CheckQueue(); errors.Is(err, ErrServiceDegraded) {
+		services["queue"] = "degraded"
+	} else if err != nil {
 		services["queue"] = "error"
 	} else {
 		services["queue"] = "ok"
@@ -54,6 +61,9 @@ func (uc *HealthUseCase) Check(ctx context.Context) *HealthResponse {
 			status = "unhealthy"
 			break
 		}
+		if s == "degraded" {
+			status = "degraded"
+		}
 	}
 
 	return &HealthResponse{
@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type stubHealthCheckService struct {
+	database error
+	queue    error
+}
+
+func (s stubHealthCheckService) CheckDatabase(ctx context.Context) error {
+	return s.database
+}
+
+func (s stubHealthCheckService) CheckQueue() error {
+	return s.queue
+}
+
+func TestHealthCheck(t *testing.T) {
+	errDown := errors.New("connection refused")
+	errReconnecting := fmt.Errorf("%w: RabbitMQ connection lost, reconnecting", ErrServiceDegraded)
+
+	tests := []struct {
+		name     string
+		service  stubHealthCheckService
+		status   string
+		database string
+		queue    string
+	}{
+		{"all up", stubHealthCheckService{}, "healthy", "ok", "ok"},
+		{"queue reconnecting", stubHealthCheckService{queue: errReconnecting}, "degraded", "ok", "degraded"},
+		{"queue down", stubHealthCheckService{queue: errDown}, "unhealthy", "ok", "error"},
+		{"database down while the queue reconnects", stubHealthCheckService{database: errDown, queue: errReconnecting}, "unhealthy", "error", "degraded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := NewHealthUseCase(tt.service).Check(context.Background())
+			if health.Status != tt.status || health.Services["database"] != tt.database || health.Services["queue"] != tt.queue {
+				t.Errorf("health = %s %v, want %s with database %s and queue %s",
+					health.Status, health.Services, tt.status, tt.database, tt.queue)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"reflect"
+	"strings"
+
+	"menu-parser/internal/domain/entity"
+)
+
+// diffMenus compares target against base. Products are matched by ExtID and,
+// failing that, by name; attributes and attribute groups are matched by name.
+// A nil base is treated as an empty menu.
+func diffMenus(base, target *entity.Menu) *entity.MenuDiff {
+	if base == nil {
+		base = &entity.Menu{}
+	}
+
+	diff := &entity.MenuDiff{
+		ProductsAdded:             []entity.Product{},
+		ProductsRemoved:           []entity.Product{},
+		ProductsRenamed:           []entity.ProductRename{},
+		ProductsRepriced:          []entity.ProductReprice{},
+		ProductsAttributesChanged: []entity.ProductAttributesChange{},
+		AttributesAdded:           []entity.Attribute{},
+		AttributesRemoved:         []entity.Attribute{},
+		AttributesGroupsAdded:     []entity.AttributesGroup{},
+		AttributesGroupsRemoved:   []entity.AttributesGroup{},
+		AttributesGroupsChanged:   []entity.AttributesGroupChange{},
+	}
+	if !base.ID.IsZero() {
+		baseID := base.ID
+		diff.BaseMenuID = &baseID
+	}
+	if !target.ID.IsZero() {
+		targetID := target.ID
+		diff.TargetMenuID = &targetID
+	}
+
+	diffProducts(diff, base.Products, target.Products)
+	diff.AttributesAdded, diff.AttributesRemoved = diffAttributes(base.Attributes, target.Attributes)
+	diffAttributesGroups(diff, base.AttributesGroups, target.AttributesGroups)
+
+	return diff
+}
+
+func diffProducts(diff *entity.MenuDiff, base, target []entity.Product) {
+	byExtID := make(map[string]int, len(base))
+	byName := make(map[string][]int, len(base))
+	for i, product := range base {
+		byExtID[product.ExtID] = i
+		name := normalizeName(product.Name)
+		byName[name] = append(byName[name], i)
+	}
+
+	matched := make([]bool, len(base))
+	match := func(product entity.Product) (int, bool) {
+		if i, ok := byExtID[product.ExtID]; ok && !matched[i] {
+			return i, true
+		}
+		for _, i := range byName[normalizeName(product.Name)] {
+			if !matched[i] {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	for _, product := range target {
+		i, ok := match(product)
+		if !ok {
+			diff.ProductsAdded = append(diff.ProductsAdded, product)
+			continue
+		}
+		matched[i] = true
+		old := base[i]
+
+		if old.Name != product.Name {
+			diff.ProductsRenamed = append(diff.ProductsRenamed, entity.ProductRename{
+				ExtID:   product.ExtID,
+				OldName: old.Name,
+				NewName: product.Name,
+			})
+		}
+		if old.Price != product.Price || old.PriceOld != product.PriceOld {
+			diff.ProductsRepriced = append(diff.ProductsRepriced, entity.ProductReprice{
+				ExtID:       product.ExtID,
+				Name:        product.Name,
+				OldPrice:    old.Price,
+				NewPrice:    product.Price,
+				OldPriceOld: old.PriceOld,
+				NewPriceOld: product.PriceOld,
+			})
+		}
+		if !reflect.DeepEqual(normalizeAttributes(old.Attributes), normalizeAttributes(product.Attributes)) {
+			diff.ProductsAttributesChanged = append(diff.ProductsAttributesChanged, entity.ProductAttributesChange{
+				ExtID:         product.ExtID,
+				Name:          product.Name,
+				OldAttributes: old.Attributes,
+				NewAttributes: product.Attributes,
+			})
+		}
+	}
+
+	for i, product := range base {
+		if !matched[i] {
+			diff.ProductsRemoved = append(diff.ProductsRemoved, product)
+		}
+	}
+}
+
+func diffAttributes(base, target []entity.Attribute) (added, removed []entity.Attribute) {
+	added = []entity.Attribute{}
+	removed = []entity.Attribute{}
+
+	baseNames := make(map[string]bool, len(base))
+	for _, attr := range base {
+		baseNames[normalizeName(attr.Name)] = true
+	}
+	targetNames := make(map[string]bool, len(target))
+	for _, attr := range target {
+		targetNames[normalizeName(attr.Name)] = true
+		if !baseNames[normalizeName(attr.Name)] {
+			added = append(added, attr)
+		}
+	}
+	for _, attr := range base {
+		if !targetNames[normalizeName(attr.Name)] {
+			removed = append(removed, attr)
+		}
+	}
+
+	return added, removed
+}
+
+func diffAttributesGroups(diff *entity.MenuDiff, base, target []entity.AttributesGroup) {
+	baseGroups := make(map[string]entity.AttributesGroup, len(base))
+	for _, group := range base {
+		baseGroups[normalizeName(group.Name)] = group
+	}
+	targetNames := make(map[string]bool, len(target))
+
+	for _, group := range target {
+		name := normalizeName(group.Name)
+		targetNames[name] = true
+
+		old, ok := baseGroups[name]
+		if !ok {
+			diff.AttributesGroupsAdded = append(diff.AttributesGroupsAdded, group)
+			continue
+		}
+
+		added, removed := diffAttributes(old.Attributes, group.Attributes)
+		if len(added) > 0 || len(removed) > 0 || old.IsRequired != group.IsRequired {
+			diff.AttributesGroupsChanged = append(diff.AttributesGroupsChanged, entity.AttributesGroupChange{
+				Name:              group.Name,
+				AttributesAdded:   added,
+				AttributesRemoved: removed,
+				OldIsRequired:     old.IsRequired,
+				NewIsRequired:     group.IsRequired,
+			})
+		}
+	}
+
+	for _, group := range base {
+		if !targetNames[normalizeName(group.Name)] {
+			diff.AttributesGroupsRemoved = append(diff.AttributesGroupsRemoved, group)
+		}
+	}
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDiffMenus(t *testing.T) {
+	base := &entity.Menu{
+		ID: primitive.NewObjectID(),
+		Products: []entity.Product{
+			{ExtID: "1", Name: "Суп", Price: 200},
+			{ExtID: "2", Name: "Салат", Price: 300},
+			{ExtID: "3", Name: "Компот", Price: 100, Attributes: map[string]interface{}{"options": []interface{}{"0,5 л"}}},
+			{ExtID: "old-4", Name: "Чай  зелёный", Price: 90},
+			{ExtID: "5", Name: "Пирог", Price: 150},
+		},
+		Attributes: []entity.Attribute{{ID: "a1", Name: "Острый"}, {ID: "a2", Name: "Без лука"}},
+		AttributesGroups: []entity.AttributesGroup{
+			{Name: "Соусы", Attributes: []entity.Attribute{{Name: "Кетчуп"}}},
+			{Name: "Хлеб", Attributes: []entity.Attribute{{Name: "Белый"}}},
+		},
+	}
+	target := &entity.Menu{
+		ID: primitive.NewObjectID(),
+		Products: []entity.Product{
+			{ExtID: "1", Name: "Суп дня", Price: 200},
+			{ExtID: "2", Name: "Салат", Price: 350, PriceOld: 300},
+			{ExtID: "3", Name: "Компот", Price: 100, Attributes: map[string]interface{}{"options": []string{"0,5 л", "1 л"}}},
+			// Matched by its normalized name although the ID changed
+			{ExtID: "new-4", Name: "чай зелёный", Price: 90},
+			{ExtID: "6", Name: "Морс", Price: 120},
+		},
+		Attributes: []entity.Attribute{{ID: "a1", Name: " острый "}, {ID: "a3", Name: "Веган"}},
+		AttributesGroups: []entity.AttributesGroup{
+			{Name: "Соусы", Attributes: []entity.Attribute{{Name: "Кетчуп"}, {Name: "Горчица"}}, IsRequired: true},
+			{Name: "Напитки", Attributes: []entity.Attribute{{Name: "Вода"}}},
+		},
+	}
+
+	diff := diffMenus(base, target)
+
+	if diff.BaseMenuID == nil || *diff.BaseMenuID != base.ID || diff.TargetMenuID == nil || *diff.TargetMenuID != target.ID {
+		t.Errorf("diff menus %v -> %v, want %s -> %s", diff.BaseMenuID, diff.TargetMenuID, base.ID.Hex(), target.ID.Hex())
+	}
+	if ids := extIDs(diff.ProductsAdded); len(ids) != 1 || ids[0] != "6" {
+		t.Errorf("added products = %v, want [6]", ids)
+	}
+	if ids := extIDs(diff.ProductsRemoved); len(ids) != 1 || ids[0] != "5" {
+		t.Errorf("removed products = %v, want [5]", ids)
+	}
+	if len(diff.ProductsRenamed) != 2 || diff.ProductsRenamed[0].ExtID != "1" || diff.ProductsRenamed[0].NewName != "Суп дня" {
+		t.Errorf("renamed products = %+v, want 1 and new-4", diff.ProductsRenamed)
+	}
+	if len(diff.ProductsRepriced) != 1 || diff.ProductsRepriced[0].ExtID != "2" || diff.ProductsRepriced[0].NewPrice != 350 {
+		t.Errorf("repriced products = %+v, want 2", diff.ProductsRepriced)
+	}
+	if len(diff.ProductsAttributesChanged) != 1 || diff.ProductsAttributesChanged[0].ExtID != "3" {
+		t.Errorf("products with changed attributes = %+v, want 3", diff.ProductsAttributesChanged)
+	}
+	if len(diff.AttributesAdded) != 1 || diff.AttributesAdded[0].Name != "Веган" ||
+		len(diff.AttributesRemoved) != 1 || diff.AttributesRemoved[0].Name != "Без лука" {
+		t.Errorf("attributes added %+v, removed %+v; want Веган and Без лука", diff.AttributesAdded, diff.AttributesRemoved)
+	}
+	if len(diff.AttributesGroupsAdded) != 1 || diff.AttributesGroupsAdded[0].Name != "Напитки" ||
+		len(diff.AttributesGroupsRemoved) != 1 || diff.AttributesGroupsRemoved[0].Name != "Хлеб" {
+		t.Errorf("groups added %+v, removed %+v; want Напитки and Хлеб", diff.AttributesGroupsAdded, diff.AttributesGroupsRemoved)
+	}
+	if len(diff.AttributesGroupsChanged) != 1 {
+		t.Fatalf("changed groups = %+v, want Соусы", diff.AttributesGroupsChanged)
+	}
+	if change := diff.AttributesGroupsChanged[0]; change.Name != "Соусы" || len(change.AttributesAdded) != 1 || !change.NewIsRequired {
+		t.Errorf("changed group = %+v, want Горчица added and the group required", change)
+	}
+}
+
+func TestDiffMenusAgainstNoMenu(t *testing.T) {
+	target := &entity.Menu{Products: []entity.Product{{ExtID: "1", Name: "Суп"}}}
+
+	diff := diffMenus(nil, target)
+	if diff.BaseMenuID != nil || len(diff.ProductsAdded) != 1 || len(diff.ProductsRemoved) != 0 {
+		t.Errorf("diff = %+v, want the product added without a base menu", diff)
+	}
+}
+
+// A merge keeps products that left the sheet, so the diff of the stored menu
+// must not report them removed the way a diff of the parsed sheet would
+func TestDiffMenusOfMergedMenu(t *testing.T) {
+	previous := &entity.Menu{
+		ID: primitive.NewObjectID(),
+		Products: []entity.Product{
+			{ExtID: "1", Name: "Суп", Price: 200, Status: string(entity.ProductStatusAvailable)},
+			{ExtID: "2", Name: "Салат", Price: 300, Status: string(entity.ProductStatusAvailable)},
+		},
+	}
+	parsed := &entity.Menu{
+		Products: []entity.Product{{ExtID: "1", Name: "Суп", Price: 250, Status: string(entity.ProductStatusAvailable)}},
+	}
+
+	merged, _ := mergeMenu(previous, parsed, "user", "task:0", time.Now())
+	diff := diffMenus(previous, merged)
+
+	if len(diff.ProductsRemoved) != 0 {
+		t.Errorf("removed products = %v, want none: the merge kept them", extIDs(diff.ProductsRemoved))
+	}
+	if len(diff.ProductsRepriced) != 1 || diff.ProductsRepriced[0].ExtID != "1" {
+		t.Errorf("repriced products = %+v, want 1", diff.ProductsRepriced)
+	}
+	if diff.TargetMenuID == nil || *diff.TargetMenuID != previous.ID {
+		t.Errorf("diff target = %v, want the merged menu %s", diff.TargetMenuID, previous.ID.Hex())
+	}
+}
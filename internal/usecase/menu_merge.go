@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+)
+
+// mergeMenu applies a freshly parsed menu on top of the previous one. Names,
+// prices and options are refreshed, operator-set statuses are kept, products
+// missing from the sheet are marked deleted until they reappear in it and
+// every change is audited under the given correlation ID.
+func mergeMenu(previous, parsed *entity.Menu, userID, correlationID string, now time.Time) (*entity.Menu, []entity.ProductStatusAudit) {
+	merged := *previous
+	merged.Name = parsed.Name
+	merged.AttributesGroups = parsed.AttributesGroups
+	merged.Attributes = parsed.Attributes
+	merged.UpdatedAt = now
+
+	existing := make(map[string]entity.Product, len(previous.Products))
+	for _, product := range previous.Products {
+		existing[product.ExtID] = product
+	}
+
+	var audits []entity.ProductStatusAudit
+	newAudit := func(product entity.Product, eventType entity.ProductEventType, oldStatus, newStatus, reason string) entity.ProductStatusAudit {
+		return entity.ProductStatusAudit{
+			RestaurantID:  previous.RestaurantID,
+			MenuID:        previous.ID,
+			ProductID:     product.ExtID,
+			ProductName:   product.Name,
+			CorrelationID: correlationID,
+			EventType:     eventType,
+			OldStatus:     oldStatus,
+			NewStatus:     newStatus,
+			Reason:        reason,
+			UserID:        userID,
+			Timestamp:     now,
+		}
+	}
+
+	products := make([]entity.Product, 0, len(parsed.Products))
+	seen := make(map[string]bool, len(parsed.Products))
+	for _, product := range parsed.Products {
+		seen[product.ExtID] = true
+
+		old, ok := existing[product.ExtID]
+		if !ok {
+			products = append(products, product)
+			audits = append(audits, newAudit(product, entity.EventTypeProductCreated, "", product.Status, "added_from_sheet"))
+			continue
+		}
+
+		changes := describeProductChanges(old, product)
+		if old.MissingFromSheet {
+			// Deleted by an earlier merge only because it had left the sheet,
+			// so it comes back with the status parsed from the sheet
+			reason := "returned_to_sheet"
+			if changes != "" {
+				reason += "; " + changes
+			}
+			audits = append(audits, newAudit(product, entity.EventTypeProductUpdated, old.Status, product.Status, reason))
+		} else {
+			product.Status = old.Status
+			if changes != "" {
+				audits = append(audits, newAudit(product, entity.EventTypeProductUpdated, old.Status, product.Status, changes))
+			}
+		}
+		products = append(products, product)
+	}
+
+	for _, product := range previous.Products {
+		if seen[product.ExtID] {
+			continue
+		}
+
+		oldStatus := product.Status
+		if oldStatus != string(entity.ProductStatusDeleted) {
+			product.Status = string(entity.ProductStatusDeleted)
+			product.MissingFromSheet = true
+			audits = append(audits, newAudit(product, entity.EventTypeProductDeleted, oldStatus, product.Status, "missing_from_sheet"))
+		}
+		products = append(products, product)
+	}
+
+	merged.Products = products
+	return &merged, audits
+}
+
+// describeProductChanges lists the sheet-driven field changes between two versions of a product
+func describeProductChanges(old, updated entity.Product) string {
+	var changes []string
+	if old.Name != updated.Name {
+		changes = append(changes, fmt.Sprintf("name: %q -> %q", old.Name, updated.Name))
+	}
+	if old.Category != updated.Category {
+		changes = append(changes, fmt.Sprintf("category: %q -> %q", old.Category, updated.Category))
+	}
+	if old.Price != updated.Price {
+		changes = append(changes, fmt.Sprintf("price: %v -> %v", old.Price, updated.Price))
+	}
+	if old.PriceOld != updated.PriceOld {
+		changes = append(changes, fmt.Sprintf("price_old: %v -> %v", old.PriceOld, updated.PriceOld))
+	}
+	if !reflect.DeepEqual(normalizeAttributes(old.Attributes), normalizeAttributes(updated.Attributes)) {
+		changes = append(changes, "attributes changed")
+	}
+	return strings.Join(changes, "; ")
+}
+
+// normalizeAttributes makes attributes read back from MongoDB comparable with freshly parsed ones
+func normalizeAttributes(attributes map[string]interface{}) map[string]string {
+	normalized := make(map[string]string, len(attributes))
+	for key, value := range attributes {
+		normalized[key] = fmt.Sprintf("%v", value)
+	}
+	return normalized
+}
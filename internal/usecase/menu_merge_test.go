@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+)
+
+func TestMergeMenuStatuses(t *testing.T) {
+	available := string(entity.ProductStatusAvailable)
+	notAvailable := string(entity.ProductStatusNotAvailable)
+	deleted := string(entity.ProductStatusDeleted)
+
+	previous := &entity.Menu{
+		RestaurantID: "r1",
+		Products: []entity.Product{
+			{ExtID: "stopped", Name: "Суп", Status: notAvailable},
+			{ExtID: "operator-deleted", Name: "Салат", Status: deleted},
+			{ExtID: "left-sheet", Name: "Пирог", Status: deleted, MissingFromSheet: true},
+			{ExtID: "dropped", Name: "Компот", Status: available},
+		},
+	}
+	parsed := &entity.Menu{
+		Products: []entity.Product{
+			{ExtID: "stopped", Name: "Суп", Status: available},
+			{ExtID: "operator-deleted", Name: "Салат", Status: available},
+			{ExtID: "left-sheet", Name: "Пирог", Price: 120, Status: available},
+		},
+	}
+
+	merged, audits := mergeMenu(previous, parsed, "user", "task", time.Now())
+
+	want := map[string]struct {
+		status  string
+		missing bool
+	}{
+		"stopped":          {notAvailable, false},
+		"operator-deleted": {deleted, false},
+		"left-sheet":       {available, false},
+		"dropped":          {deleted, true},
+	}
+	if len(merged.Products) != len(want) {
+		t.Fatalf("got %d products, want %d", len(merged.Products), len(want))
+	}
+	for _, product := range merged.Products {
+		w := want[product.ExtID]
+		if product.Status != w.status || product.MissingFromSheet != w.missing {
+			t.Errorf("product %s: status %q missing %v, want %q missing %v",
+				product.ExtID, product.Status, product.MissingFromSheet, w.status, w.missing)
+		}
+	}
+
+	reasons := make(map[string]string, len(audits))
+	for _, audit := range audits {
+		reasons[audit.ProductID] = audit.Reason
+	}
+	if got := reasons["left-sheet"]; got != "returned_to_sheet; price: 0 -> 120" {
+		t.Errorf("returned product audit reason = %q", got)
+	}
+	if got := reasons["dropped"]; got != "missing_from_sheet" {
+		t.Errorf("dropped product audit reason = %q", got)
+	}
+	if _, ok := reasons["operator-deleted"]; ok {
+		t.Error("unchanged product kept deleted by an operator must not be audited")
+	}
+}
@@ -2,11 +2,15 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
 
+	"menu-parser/internal/domain/apperror"
 	"menu-parser/internal/domain/entity"
 	"menu-parser/internal/domain/repository"
 	"menu-parser/internal/domain/service"
@@ -14,57 +18,223 @@ import (
 
 // MenuUseCase handles menu-related business logic
 type MenuUseCase struct {
-	menuRepo     repository.MenuRepository
-	taskRepo     repository.TaskRepository
-	parser       service.SheetsParser
-	queuePub     service.QueuePublisher
+	menuRepo       repository.MenuRepository
+	taskRepo       repository.TaskRepository
+	mappingRepo    repository.ColumnMappingRepository
+	auditRepo      repository.AuditRepository
+	restaurantRepo repository.RestaurantRepository
+	outboxRepo     repository.OutboxRepository
+	transactor     repository.Transactor
+	parser         service.SheetsParser
+	// callbacksEnabled is set when a webhook secret is configured to sign callbacks
+	callbacksEnabled bool
 }
 
 // NewMenuUseCase creates a new MenuUseCase
 func NewMenuUseCase(
 	menuRepo repository.MenuRepository,
 	taskRepo repository.TaskRepository,
+	mappingRepo repository.ColumnMappingRepository,
+	auditRepo repository.AuditRepository,
+	restaurantRepo repository.RestaurantRepository,
+	outboxRepo repository.OutboxRepository,
+	transactor repository.Transactor,
 	parser service.SheetsParser,
-	queuePub service.QueuePublisher,
+	callbacksEnabled bool,
 ) *MenuUseCase {
 	return &MenuUseCase{
-		menuRepo: menuRepo,
-		taskRepo: taskRepo,
-		parser:   parser,
-		queuePub: queuePub,
+		menuRepo:         menuRepo,
+		taskRepo:         taskRepo,
+		mappingRepo:      mappingRepo,
+		auditRepo:        auditRepo,
+		restaurantRepo:   restaurantRepo,
+		outboxRepo:       outboxRepo,
+		transactor:       transactor,
+		parser:           parser,
+		callbacksEnabled: callbacksEnabled,
 	}
 }
 
-// CreateParsingTask creates a new parsing task and queues it
-func (uc *MenuUseCase) CreateParsingTask(ctx context.Context, spreadsheetID, restaurantName string) (string, error) {
+var (
+	// ErrInvalidParseRequest is wrapped by validation errors of parse requests
+	ErrInvalidParseRequest = apperror.New(apperror.KindInvalidArgument, "invalid_parse_request", "invalid parse request")
+	// ErrCallbacksDisabled is returned for a callback_url while no webhook
+	// secret is configured, as callbacks would go out unsigned
+	ErrCallbacksDisabled = apperror.New(apperror.KindInvalidArgument, "callbacks_disabled", "callback_url is not accepted: webhooks are not configured")
+	// ErrTaskNotQueued is returned when a task to process is no longer queued,
+	// e.g. a duplicate message for a task another worker already took
+	ErrTaskNotQueued = apperror.New(apperror.KindConflict, "task_not_queued", "task is not queued")
+	// ErrTaskCancelled is returned when a task was cancelled while being processed
+	ErrTaskCancelled = apperror.New(apperror.KindConflict, "task_cancelled", "task was cancelled")
+	// ErrTaskNotCancellable is returned for tasks that already finished
+	ErrTaskNotCancellable = apperror.New(apperror.KindConflict, "task_not_cancellable", "only queued or processing tasks can be cancelled")
+	// ErrTaskNotRetryable is returned for tasks that did not fail and were not cancelled
+	ErrTaskNotRetryable = apperror.New(apperror.KindConflict, "task_not_retryable", "only failed or cancelled tasks can be retried")
+	// ErrInvalidTaskQuery is wrapped by validation errors of task listings
+	ErrInvalidTaskQuery = apperror.New(apperror.KindInvalidArgument, "invalid_task_query", "invalid task query")
+)
+
+const (
+	defaultTaskPageSize = 50
+	maxTaskPageSize     = 200
+	// maxMergeAttempts bounds how often a merge is redone after the menu
+	// was modified concurrently
+	maxMergeAttempts = 5
+	// cancelPollInterval is how often a running parse checks whether its
+	// task was cancelled
+	cancelPollInterval = time.Second
+)
+
+// CreateParsingTaskInput holds the parameters of a parse request
+type CreateParsingTaskInput struct {
+	// RestaurantID refers to a registered restaurant whose settings fill in omitted fields
+	RestaurantID   string
+	SpreadsheetID  string
+	RestaurantName string
+	ColumnMapping  *entity.ColumnMapping
+	MappingProfile string
+	Sheets         []string
+	AllSheets      bool
+	Mode           entity.ParseMode
+	// SkipActivation keeps a newly created version inactive until it is activated explicitly
+	SkipActivation bool
+	// CreatedBy is recorded on the task and on audit records of a merge
+	CreatedBy string
+	// CallbackURL is notified once the task completed or failed for good
+	CallbackURL string
+}
+
+// CreateParsingTask creates a new parsing task and queues it through the
+// outbox, written in the same transaction as the task
+func (uc *MenuUseCase) CreateParsingTask(ctx context.Context, input *CreateParsingTaskInput) (string, error) {
+	if input.RestaurantID != "" {
+		restaurant, err := uc.restaurantRepo.GetByID(ctx, input.RestaurantID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get restaurant: %w", err)
+		}
+		applyRestaurantDefaults(input, restaurant)
+	}
+
+	if input.SpreadsheetID == "" || input.RestaurantName == "" {
+		return "", fmt.Errorf("%w: spreadsheet_id and restaurant_name are required without a restaurant_id", ErrInvalidParseRequest)
+	}
+	if input.AllSheets && len(input.Sheets) > 0 {
+		return "", fmt.Errorf("%w: sheets and all_sheets are mutually exclusive", ErrInvalidParseRequest)
+	}
+	if input.CallbackURL != "" {
+		if !uc.callbacksEnabled {
+			return "", ErrCallbacksDisabled
+		}
+		callbackURL, err := url.Parse(input.CallbackURL)
+		if err != nil || (callbackURL.Scheme != "http" && callbackURL.Scheme != "https") || callbackURL.Host == "" {
+			return "", fmt.Errorf("%w: callback_url must be an absolute http or https URL", ErrInvalidParseRequest)
+		}
+	}
+
+	restaurantKey := input.RestaurantID
+	if restaurantKey == "" {
+		restaurantKey = input.RestaurantName
+	}
+
+	if input.ColumnMapping == nil && input.MappingProfile != "" {
+		if _, err := uc.mappingRepo.GetByName(ctx, restaurantKey, input.MappingProfile); err != nil {
+			return "", fmt.Errorf("failed to get column mapping profile: %w", err)
+		}
+	}
+
+	mode := input.Mode
+	if mode == "" {
+		mode = entity.ParseModeCreate
+	}
+
 	taskID := uuid.New().String()
-	
+
 	task := &entity.ParsingTask{
 		ID:             taskID,
 		Status:         entity.TaskStatusQueued,
-		SpreadsheetID:  spreadsheetID,
-		RestaurantName: restaurantName,
+		SpreadsheetID:  input.SpreadsheetID,
+		RestaurantID:   input.RestaurantID,
+		RestaurantName: input.RestaurantName,
+		ColumnMapping:  input.ColumnMapping,
+		MappingProfile: input.MappingProfile,
+		Sheets:         input.Sheets,
+		AllSheets:      input.AllSheets,
+		Mode:           mode,
+		SkipActivation: input.SkipActivation,
 		RetryCount:     0,
+		CallbackURL:    input.CallbackURL,
+		CreatedBy:      input.CreatedBy,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
 
-	if err := uc.taskRepo.Create(ctx, task); err != nil {
-		return "", fmt.Errorf("failed to create task: %w", err)
-	}
-
-	if err := uc.queuePub.PublishMenuParsingTask(taskID); err != nil {
-		return "", fmt.Errorf("failed to queue task: %w", err)
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.taskRepo.Create(ctx, task); err != nil {
+			return fmt.Errorf("failed to create task: %w", err)
+		}
+		if err := uc.outboxRepo.Create(ctx, entity.NewMenuParsingOutboxMessage(taskID)); err != nil {
+			return fmt.Errorf("failed to queue task: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
 	return taskID, nil
 }
 
+// applyRestaurantDefaults fills fields the request left empty from the restaurant's settings
+func applyRestaurantDefaults(input *CreateParsingTaskInput, restaurant *entity.Restaurant) {
+	input.RestaurantName = restaurant.Name
+
+	if input.SpreadsheetID == "" {
+		input.SpreadsheetID = restaurant.SpreadsheetID
+	}
+	if input.ColumnMapping == nil && input.MappingProfile == "" {
+		input.MappingProfile = restaurant.Settings.MappingProfile
+	}
+	if !input.AllSheets && len(input.Sheets) == 0 {
+		input.Sheets = restaurant.Settings.Sheets
+		input.AllSheets = restaurant.Settings.AllSheets
+	}
+	if input.Mode == "" {
+		input.Mode = restaurant.Settings.ParseMode
+	}
+}
+
 // GetTaskStatus retrieves the status of a parsing task
 func (uc *MenuUseCase) GetTaskStatus(ctx context.Context, taskID string) (*entity.ParsingTask, error) {
 	return uc.taskRepo.GetByID(ctx, taskID)
 }
 
+// ListTasks returns a page of parsing tasks matching the filter, newest first
+// unless sorted ascending, and the cursor of the next page
+func (uc *MenuUseCase) ListTasks(ctx context.Context, filter repository.TaskFilter) ([]entity.ParsingTask, string, error) {
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && filter.CreatedFrom.After(filter.CreatedTo) {
+		return nil, "", fmt.Errorf("%w: from must not be after to", ErrInvalidTaskQuery)
+	}
+	switch filter.SortBy {
+	case "", repository.TaskSortCreatedAt, repository.TaskSortUpdatedAt:
+	default:
+		return nil, "", fmt.Errorf("%w: cannot sort by %q", ErrInvalidTaskQuery, filter.SortBy)
+	}
+
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = defaultTaskPageSize
+	case filter.Limit > maxTaskPageSize:
+		filter.Limit = maxTaskPageSize
+	}
+
+	tasks, nextCursor, err := uc.taskRepo.List(ctx, filter)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list parsing tasks: %w", err)
+	}
+
+	return tasks, nextCursor, nil
+}
+
 // GetMenu retrieves a menu by ID
 func (uc *MenuUseCase) GetMenu(ctx context.Context, menuID string) (*entity.Menu, error) {
 	return uc.menuRepo.GetByID(ctx, menuID)
@@ -77,30 +247,362 @@ func (uc *MenuUseCase) ProcessMenuParsing(ctx context.Context, taskID string) er
 		return fmt.Errorf("failed to get task: %w", err)
 	}
 
-	// Update status to processing
-	if err := uc.taskRepo.UpdateStatus(ctx, taskID, entity.TaskStatusProcessing, nil, ""); err != nil {
+	// Update status to processing, unless another worker already did
+	claimed, err := uc.taskRepo.ClaimForProcessing(ctx, taskID)
+	if err != nil {
 		return fmt.Errorf("failed to update task status: %w", err)
 	}
+	if !claimed {
+		return ErrTaskNotQueued
+	}
 
-	// Parse menu
-	menu, err := uc.parser.ParseMenu(ctx, task.SpreadsheetID, task.RestaurantName)
+	mapping, err := uc.resolveColumnMapping(ctx, task)
+	if err != nil {
+		uc.failTask(ctx, task, err)
+		return fmt.Errorf("failed to resolve column mapping: %w", err)
+	}
+
+	// Parse menu, stopping early if the task is cancelled meanwhile
+	parseCtx, stopWatching := uc.watchCancellation(ctx, taskID)
+	menu, err := uc.parser.ParseMenu(parseCtx, &service.ParseMenuRequest{
+		SpreadsheetID:  task.SpreadsheetID,
+		RestaurantID:   task.RestaurantKey(),
+		RestaurantName: task.RestaurantName,
+		ColumnMapping:  mapping,
+		Sheets:         task.Sheets,
+		AllSheets:      task.AllSheets,
+	})
+	stopWatching()
+	if errors.Is(context.Cause(parseCtx), ErrTaskCancelled) {
+		return ErrTaskCancelled
+	}
 	if err != nil {
-		uc.taskRepo.UpdateStatus(ctx, taskID, entity.TaskStatusFailed, nil, err.Error())
+		uc.failTask(ctx, task, err)
 		return fmt.Errorf("failed to parse menu: %w", err)
 	}
 
+	// A task cancelled since the last poll must not store a menu either. A
+	// cancellation arriving after this point leaves the saved menu in place,
+	// but the task stays cancelled and no webhook is sent.
+	if err := uc.checkCancelled(ctx, taskID); err != nil {
+		return err
+	}
+
+	// Keep product IDs stable across re-parses of the same restaurant
+	previous, err := uc.menuRepo.GetActiveByRestaurant(ctx, menu.RestaurantID)
+	if err != nil {
+		uc.failTask(ctx, task, err)
+		return fmt.Errorf("failed to get previous menu: %w", err)
+	}
+	reuseProductExtIDs(menu, previous)
+
 	// Save menu
-	savedMenu, err := uc.menuRepo.Create(ctx, menu)
+	var savedMenu *entity.Menu
+	if task.Mode == entity.ParseModeMerge && previous != nil {
+		savedMenu, err = uc.mergeIntoMenu(ctx, task, previous, menu)
+	} else {
+		savedMenu, err = uc.createMenuVersion(ctx, menu, !task.SkipActivation)
+	}
 	if err != nil {
-		uc.taskRepo.UpdateStatus(ctx, taskID, entity.TaskStatusFailed, nil, err.Error())
+		uc.failTask(ctx, task, err)
 		return fmt.Errorf("failed to save menu: %w", err)
 	}
 
+	// Diff what was stored: a merge keeps products the sheet no longer lists
+	diff := diffMenus(previous, savedMenu)
+	if err := uc.taskRepo.SaveDiff(ctx, taskID, diff); err != nil {
+		// The menu is already stored; failing the task here would only create a duplicate on retry
+		log.Printf("Failed to save menu diff for task %s: %v", taskID, err)
+	}
+
 	// Update task status to completed
-	if err := uc.taskRepo.UpdateStatus(ctx, taskID, entity.TaskStatusCompleted, &savedMenu.ID, ""); err != nil {
+	task.Status = entity.TaskStatusCompleted
+	task.MenuID = &savedMenu.ID
+	var completed bool
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		completed, err = uc.taskRepo.UpdateStatus(ctx, taskID, entity.TaskStatusCompleted, &savedMenu.ID, "")
+		if err != nil || !completed {
+			return err
+		}
+		return uc.notifyCallback(ctx, task)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to update task status: %w", err)
 	}
+	if !completed {
+		return ErrTaskCancelled
+	}
 
 	return nil
 }
 
+// FailTask marks the task failed for good and notifies its callback URL. A
+// task cancelled meanwhile is left cancelled without a notification.
+func (uc *MenuUseCase) FailTask(ctx context.Context, task *entity.ParsingTask, errorCode, errorMsg string) error {
+	task.Status = entity.TaskStatusFailed
+	task.ErrorCode = errorCode
+	task.ErrorMessage = errorMsg
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		failed, err := uc.taskRepo.MarkFailed(ctx, task.ID, errorCode, errorMsg)
+		if err != nil || !failed {
+			return err
+		}
+		return uc.notifyCallback(ctx, task)
+	})
+}
+
+// failTask records the code and the typed message of the error on the task,
+// where clients can read them. Only a permanent error fails the task; a
+// transient one is recorded without a status change and leaves the consumer
+// to retry the task.
+func (uc *MenuUseCase) failTask(ctx context.Context, task *entity.ParsingTask, err error) {
+	if !apperror.IsPermanent(err) {
+		if recordErr := uc.taskRepo.RecordError(ctx, task.ID, apperror.CodeOf(err), apperror.MessageOf(err)); recordErr != nil {
+			log.Printf("Failed to record error of task %s: %v", task.ID, recordErr)
+		}
+		return
+	}
+	if err := uc.FailTask(ctx, task, apperror.CodeOf(err), apperror.MessageOf(err)); err != nil {
+		log.Printf("Failed to mark task %s failed: %v", task.ID, err)
+	}
+}
+
+// notifyCallback queues the webhook of a finished task through the outbox
+func (uc *MenuUseCase) notifyCallback(ctx context.Context, task *entity.ParsingTask) error {
+	if task.CallbackURL == "" {
+		return nil
+	}
+	if err := uc.outboxRepo.Create(ctx, entity.NewTaskWebhookOutboxMessage(task)); err != nil {
+		return fmt.Errorf("failed to queue webhook: %w", err)
+	}
+	return nil
+}
+
+// CancelTask stops a queued or processing task. A worker processing it stops
+// before saving the menu.
+func (uc *MenuUseCase) CancelTask(ctx context.Context, taskID, userID string) (*entity.ParsingTask, error) {
+	cancelled, err := uc.taskRepo.Cancel(ctx, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := uc.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, fmt.Errorf("%w: task is %s", ErrTaskNotCancellable, task.Status)
+	}
+
+	return task, nil
+}
+
+// RetryTask queues a failed or cancelled task again with a fresh retry budget
+func (uc *MenuUseCase) RetryTask(ctx context.Context, taskID, userID string) (*entity.ParsingTask, error) {
+	var requeued bool
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		requeued, err = uc.taskRepo.RequeueFailedOrCancelled(ctx, taskID, userID)
+		if err != nil || !requeued {
+			return err
+		}
+		if err := uc.outboxRepo.Create(ctx, entity.NewMenuParsingOutboxMessage(taskID)); err != nil {
+			return fmt.Errorf("failed to queue task: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := uc.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !requeued {
+		return nil, fmt.Errorf("%w: task is %s", ErrTaskNotRetryable, task.Status)
+	}
+
+	return task, nil
+}
+
+// watchCancellation returns a context that is cancelled with
+// ErrTaskCancelled once the task is cancelled. The task is polled until stop
+// is called; a failed poll is simply repeated on the next tick.
+func (uc *MenuUseCase) watchCancellation(ctx context.Context, taskID string) (context.Context, func()) {
+	watchCtx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		ticker := time.NewTicker(cancelPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-watchCtx.Done():
+				return
+			case <-ticker.C:
+				task, err := uc.taskRepo.GetByID(watchCtx, taskID)
+				if err == nil && task.Status == entity.TaskStatusCancelled {
+					cancel(ErrTaskCancelled)
+					return
+				}
+			}
+		}
+	}()
+	return watchCtx, func() { cancel(nil) }
+}
+
+// checkCancelled returns ErrTaskCancelled if the task was cancelled
+func (uc *MenuUseCase) checkCancelled(ctx context.Context, taskID string) error {
+	task, err := uc.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	if task.Status == entity.TaskStatusCancelled {
+		return ErrTaskCancelled
+	}
+	return nil
+}
+
+// DiffMenus compares a menu against another one, or against the restaurant's
+// active menu when againstID is empty
+func (uc *MenuUseCase) DiffMenus(ctx context.Context, menuID, againstID string) (*entity.MenuDiff, error) {
+	target, err := uc.menuRepo.GetByID(ctx, menuID)
+	if err != nil {
+		return nil, err
+	}
+
+	var base *entity.Menu
+	if againstID != "" {
+		base, err = uc.menuRepo.GetByID(ctx, againstID)
+	} else {
+		base, err = uc.menuRepo.GetActiveByRestaurant(ctx, target.RestaurantID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return diffMenus(base, target), nil
+}
+
+// ListMenuVersions lists all stored menu versions of a restaurant, newest first
+func (uc *MenuUseCase) ListMenuVersions(ctx context.Context, restaurantID string) ([]entity.MenuVersion, error) {
+	return uc.menuRepo.ListVersions(ctx, restaurantID)
+}
+
+// ActivateMenu makes the given menu version the active one for its restaurant
+func (uc *MenuUseCase) ActivateMenu(ctx context.Context, restaurantID, menuID string) (*entity.Menu, error) {
+	return uc.menuRepo.Activate(ctx, restaurantID, menuID)
+}
+
+// RollbackMenu activates the newest version older than the currently active one
+func (uc *MenuUseCase) RollbackMenu(ctx context.Context, restaurantID string) (*entity.Menu, error) {
+	active, err := uc.menuRepo.GetActiveByRestaurant(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	if active == nil {
+		return nil, repository.ErrMenuNotFound
+	}
+
+	versions, err := uc.menuRepo.ListVersions(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Versions are sorted newest first
+	for _, version := range versions {
+		if version.Version > 0 && version.Version < active.Version {
+			return uc.menuRepo.Activate(ctx, restaurantID, version.MenuID.Hex())
+		}
+	}
+
+	return nil, apperror.New(apperror.KindConflict, "no_older_version", "no older menu version to roll back to")
+}
+
+// createMenuVersion stores the menu as a new version and optionally makes it
+// active, in one transaction so an active pointer never misses its menu
+func (uc *MenuUseCase) createMenuVersion(ctx context.Context, menu *entity.Menu, activate bool) (*entity.Menu, error) {
+	var savedMenu *entity.Menu
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		savedMenu, err = uc.menuRepo.Create(ctx, menu)
+		if err != nil {
+			return err
+		}
+
+		if activate {
+			if _, err := uc.menuRepo.Activate(ctx, savedMenu.RestaurantID, savedMenu.ID.Hex()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return savedMenu, nil
+}
+
+// mergeIntoMenu updates the previous menu in place with the parsed one and
+// audits the changes, correlated with the parsing task. The update only
+// applies to the revision that was merged; if a product status changed in the
+// meantime the menu is read again and merged anew.
+func (uc *MenuUseCase) mergeIntoMenu(ctx context.Context, task *entity.ParsingTask, previous, parsed *entity.Menu) (*entity.Menu, error) {
+	userID := task.CreatedBy
+	if userID == "" {
+		userID = "system"
+	}
+
+	for attempt := 1; ; attempt++ {
+		// A retried or replayed task merges again onto a newer revision, so its
+		// audit records do not collide with those of an earlier merge
+		correlationID := fmt.Sprintf("%s:%d", task.ID, previous.Revision)
+		merged, audits := mergeMenu(previous, parsed, userID, correlationID, time.Now())
+
+		err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := uc.menuRepo.Update(ctx, merged); err != nil {
+				return err
+			}
+			if err := uc.auditRepo.CreateMany(ctx, audits); err != nil {
+				return fmt.Errorf("failed to create audit records: %w", err)
+			}
+			return nil
+		})
+		if err == nil {
+			return merged, nil
+		}
+		if !errors.Is(err, repository.ErrMenuModified) || attempt == maxMergeAttempts {
+			return nil, err
+		}
+
+		previous, err = uc.menuRepo.GetByID(ctx, previous.ID.Hex())
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// resolveColumnMapping picks the mapping for a task: an inline mapping wins,
+// then the named profile, then the restaurant's default profile. A nil result
+// means the parser falls back to its built-in layout.
+func (uc *MenuUseCase) resolveColumnMapping(ctx context.Context, task *entity.ParsingTask) (*entity.ColumnMapping, error) {
+	if task.ColumnMapping != nil {
+		return task.ColumnMapping, nil
+	}
+
+	profileName := task.MappingProfile
+	if profileName == "" {
+		profileName = entity.DefaultColumnMappingProfile
+	}
+
+	profile, err := uc.mappingRepo.GetByName(ctx, task.RestaurantKey(), profileName)
+	if err != nil {
+		if errors.Is(err, repository.ErrColumnMappingProfileNotFound) && task.MappingProfile == "" {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &profile.Mapping, nil
+}
@@ -0,0 +1,20 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCreateParsingTaskRefusesCallbackWithoutSecret(t *testing.T) {
+	uc := NewMenuUseCase(nil, nil, nil, nil, nil, nil, nil, nil, false)
+
+	_, err := uc.CreateParsingTask(context.Background(), &CreateParsingTaskInput{
+		SpreadsheetID:  "spreadsheet",
+		RestaurantName: "Кафе",
+		CallbackURL:    "https://example.com/hook",
+	})
+	if !errors.Is(err, ErrCallbacksDisabled) {
+		t.Errorf("error = %v, want %v", err, ErrCallbacksDisabled)
+	}
+}
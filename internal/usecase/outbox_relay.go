@@ -0,0 +1,170 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"menu-parser/internal/domain/apperror"
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+	"menu-parser/internal/domain/service"
+)
+
+const (
+	// outboxLease must outlast publishing one message, which the publish
+	// confirm and webhook timeouts bound
+	outboxLease      = 30 * time.Second
+	outboxMaxBackoff = time.Minute
+)
+
+// OutboxRelay publishes pending outbox messages to the queue and delivers task
+// webhooks. Delivery is at least once: a message published right before a
+// crash is published again after its lease expires, and consumers skip what
+// they already processed.
+type OutboxRelay struct {
+	outboxRepo    repository.OutboxRepository
+	taskRepo      repository.TaskRepository
+	queuePub      service.QueuePublisher
+	webhookSender service.WebhookSender
+	pollInterval  time.Duration
+	maxAttempts   int
+}
+
+// NewOutboxRelay creates a new OutboxRelay
+func NewOutboxRelay(
+	outboxRepo repository.OutboxRepository,
+	taskRepo repository.TaskRepository,
+	queuePub service.QueuePublisher,
+	webhookSender service.WebhookSender,
+	pollInterval time.Duration,
+	maxAttempts int,
+) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo:    outboxRepo,
+		taskRepo:      taskRepo,
+		queuePub:      queuePub,
+		webhookSender: webhookSender,
+		pollInterval:  pollInterval,
+		maxAttempts:   maxAttempts,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled
+func (r *OutboxRelay) Run(ctx context.Context) {
+	log.Println("Outbox relay started")
+
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.relayPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("Outbox relay stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayPending publishes due messages until none is left. Each message is
+// claimed right before it is published, so its lease cannot run out while
+// earlier messages are still being relayed and let another relay take it.
+func (r *OutboxRelay) relayPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		message, err := r.outboxRepo.ClaimNext(ctx, outboxLease)
+		if err != nil {
+			log.Printf("Error claiming outbox message: %v", err)
+			return
+		}
+		if message == nil {
+			return
+		}
+		r.relay(ctx, message)
+	}
+}
+
+func (r *OutboxRelay) relay(ctx context.Context, message *entity.OutboxMessage) {
+	err := r.publish(ctx, message)
+	if err == nil {
+		if err := r.outboxRepo.MarkPublished(ctx, message.ID); err != nil {
+			log.Printf("Error marking outbox message %s published: %v", message.ID.Hex(), err)
+		}
+		return
+	}
+
+	if message.Attempts >= r.maxAttempts || apperror.IsPermanent(err) {
+		log.Printf("Outbox message %s failed after %d attempts: %v", message.ID.Hex(), message.Attempts, err)
+		if err := r.outboxRepo.MarkFailed(ctx, message.ID, err.Error()); err != nil {
+			log.Printf("Error marking outbox message %s failed: %v", message.ID.Hex(), err)
+		}
+		return
+	}
+
+	log.Printf("Error publishing outbox message %s (attempt %d): %v", message.ID.Hex(), message.Attempts, err)
+	if err := r.outboxRepo.ScheduleRetry(ctx, message.ID, err.Error(), time.Now().Add(outboxBackoff(message.Attempts))); err != nil {
+		log.Printf("Error scheduling outbox message %s retry: %v", message.ID.Hex(), err)
+	}
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, message *entity.OutboxMessage) error {
+	switch message.Type {
+	case entity.OutboxMessageMenuParsingTask:
+		return r.queuePub.PublishMenuParsingTask(message.TaskID)
+	case entity.OutboxMessageProductStatusEvent:
+		if message.Event == nil {
+			return fmt.Errorf("outbox message has no event")
+		}
+		return r.queuePub.PublishProductStatusEvent(message.Event)
+	case entity.OutboxMessageTaskWebhook:
+		if message.Webhook == nil {
+			return fmt.Errorf("outbox message has no webhook")
+		}
+		return r.deliverWebhook(ctx, message)
+	default:
+		return fmt.Errorf("unknown outbox message type %q", message.Type)
+	}
+}
+
+// deliverWebhook POSTs the task outcome to its callback URL and records the
+// attempt on the task
+func (r *OutboxRelay) deliverWebhook(ctx context.Context, message *entity.OutboxMessage) error {
+	payload, err := json.Marshal(message.Webhook.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	deliveryID := message.ID.Hex()
+	statusCode, sendErr := r.webhookSender.Send(ctx, message.Webhook.URL, deliveryID, payload)
+
+	delivery := &entity.WebhookDelivery{
+		DeliveryID:  deliveryID,
+		Attempt:     message.Attempts,
+		StatusCode:  statusCode,
+		AttemptedAt: time.Now(),
+	}
+	if sendErr != nil {
+		delivery.Error = apperror.MessageOf(sendErr)
+	}
+	if err := r.taskRepo.RecordWebhookDelivery(ctx, message.TaskID, delivery); err != nil {
+		log.Printf("Error recording webhook delivery of task %s: %v", message.TaskID, err)
+	}
+
+	return sendErr
+}
+
+// outboxBackoff doubles the delay with every attempt, up to outboxMaxBackoff
+func outboxBackoff(attempts int) time.Duration {
+	delay := time.Second
+	for i := 1; i < attempts && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxBackoff {
+		delay = outboxMaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"menu-parser/internal/domain/entity"
+)
+
+// reuseProductExtIDs replaces generated ExtIDs in a freshly parsed menu with
+// the IDs the same products had in the previous menu, matched by product key.
+// Products from menus parsed before categories existed are matched by name.
+func reuseProductExtIDs(parsed, previous *entity.Menu) {
+	if previous == nil {
+		return
+	}
+
+	byKey := make(map[string][]string)
+	byName := make(map[string][]string)
+	for _, product := range previous.Products {
+		key := entity.ProductKey(product.Category, product.Name)
+		byKey[key] = append(byKey[key], product.ExtID)
+		if product.Category == "" {
+			nameKey := entity.ProductKey("", product.Name)
+			byName[nameKey] = append(byName[nameKey], product.ExtID)
+		}
+	}
+
+	used := make(map[string]bool, len(parsed.Products))
+	for _, product := range parsed.Products {
+		if !product.ExtIDGenerated {
+			used[product.ExtID] = true
+		}
+	}
+
+	for i := range parsed.Products {
+		product := &parsed.Products[i]
+		if !product.ExtIDGenerated {
+			continue
+		}
+
+		extID, ok := takeUnused(byKey, entity.ProductKey(product.Category, product.Name), used)
+		if !ok {
+			extID, ok = takeUnused(byName, entity.ProductKey("", product.Name), used)
+		}
+		if ok {
+			product.ExtID = extID
+			product.ExtIDGenerated = false
+		}
+		used[product.ExtID] = true
+	}
+}
+
+// takeUnused pops the first ID for key that is not already taken in the new menu
+func takeUnused(ids map[string][]string, key string, used map[string]bool) (string, bool) {
+	candidates := ids[key]
+	for len(candidates) > 0 {
+		extID := candidates[0]
+		candidates = candidates[1:]
+		ids[key] = candidates
+		if !used[extID] {
+			return extID, true
+		}
+	}
+	return "", false
+}
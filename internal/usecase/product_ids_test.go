@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"slices"
+	"testing"
+
+	"menu-parser/internal/domain/entity"
+)
+
+func extIDs(products []entity.Product) []string {
+	ids := make([]string, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ExtID)
+	}
+	return ids
+}
+
+func TestReuseProductExtIDs(t *testing.T) {
+	generated := func(category, name string) entity.Product {
+		return entity.Product{
+			ExtID:          entity.ProductExtIDFromKey(entity.ProductKey(category, name)),
+			ExtIDGenerated: true,
+			Category:       category,
+			Name:           name,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		previous []entity.Product
+		parsed   []entity.Product
+		want     []string
+	}{
+		{
+			name:     "same category and name",
+			previous: []entity.Product{{ExtID: "1", Category: "Супы", Name: "Борщ"}},
+			parsed:   []entity.Product{generated("Супы", "Борщ")},
+			want:     []string{"1"},
+		},
+		{
+			name:     "case and spacing ignored",
+			previous: []entity.Product{{ExtID: "1", Category: "Супы", Name: "Борщ  с мясом"}},
+			parsed:   []entity.Product{generated(" супы", "борщ с мясом ")},
+			want:     []string{"1"},
+		},
+		{
+			name:     "previous menu without categories",
+			previous: []entity.Product{{ExtID: "1", Name: "Борщ"}},
+			parsed:   []entity.Product{generated("Супы", "Борщ")},
+			want:     []string{"1"},
+		},
+		{
+			name:     "moved to another category",
+			previous: []entity.Product{{ExtID: "1", Category: "Супы", Name: "Борщ"}},
+			parsed:   []entity.Product{generated("Горячее", "Борщ")},
+			want:     []string{entity.ProductExtIDFromKey(entity.ProductKey("Горячее", "Борщ"))},
+		},
+		{
+			name: "namesakes keep their order",
+			previous: []entity.Product{
+				{ExtID: "1", Category: "Напитки", Name: "Чай"},
+				{ExtID: "2", Category: "Напитки", Name: "Чай"},
+			},
+			parsed: []entity.Product{generated("Напитки", "Чай"), generated("Напитки", "Чай")},
+			want:   []string{"1", "2"},
+		},
+		{
+			name:     "ID taken from the sheet is not reused",
+			previous: []entity.Product{{ExtID: "1", Category: "Супы", Name: "Борщ"}},
+			parsed: []entity.Product{
+				{ExtID: "1", Category: "Супы", Name: "Солянка"},
+				generated("Супы", "Борщ"),
+			},
+			want: []string{"1", entity.ProductExtIDFromKey(entity.ProductKey("Супы", "Борщ"))},
+		},
+		{
+			name:     "explicit ID kept",
+			previous: []entity.Product{{ExtID: "1", Category: "Супы", Name: "Борщ"}},
+			parsed:   []entity.Product{{ExtID: "42", Category: "Супы", Name: "Борщ"}},
+			want:     []string{"42"},
+		},
+		{
+			name:     "new product",
+			previous: []entity.Product{{ExtID: "1", Category: "Супы", Name: "Борщ"}},
+			parsed:   []entity.Product{generated("Супы", "Уха")},
+			want:     []string{entity.ProductExtIDFromKey(entity.ProductKey("Супы", "Уха"))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := &entity.Menu{Products: tt.parsed}
+			reuseProductExtIDs(parsed, &entity.Menu{Products: tt.previous})
+
+			if got := extIDs(parsed.Products); !slices.Equal(got, tt.want) {
+				t.Errorf("ExtIDs = %v, want %v", got, tt.want)
+			}
+			for _, product := range parsed.Products {
+				reused := slices.ContainsFunc(tt.previous, func(p entity.Product) bool { return p.ExtID == product.ExtID })
+				if reused && product.ExtIDGenerated {
+					t.Errorf("product %s reuses ExtID %s but is still marked generated", product.Name, product.ExtID)
+				}
+			}
+		})
+	}
+}
+
+func TestReuseProductExtIDsWithoutPreviousMenu(t *testing.T) {
+	parsed := &entity.Menu{Products: []entity.Product{{ExtID: "p1", ExtIDGenerated: true, Name: "Борщ"}}}
+	reuseProductExtIDs(parsed, nil)
+	if parsed.Products[0].ExtID != "p1" || !parsed.Products[0].ExtIDGenerated {
+		t.Errorf("product = %+v, want the generated ID kept", parsed.Products[0])
+	}
+}
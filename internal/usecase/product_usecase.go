@@ -2,35 +2,38 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
+
 	"menu-parser/internal/domain/entity"
 	"menu-parser/internal/domain/repository"
-	"menu-parser/internal/domain/service"
 )
 
 // ProductUseCase handles product-related business logic
 type ProductUseCase struct {
-	menuRepo  repository.MenuRepository
-	auditRepo repository.AuditRepository
-	queuePub  service.QueuePublisher
+	menuRepo   repository.MenuRepository
+	auditRepo  repository.AuditRepository
+	outboxRepo repository.OutboxRepository
 }
 
 // NewProductUseCase creates a new ProductUseCase
 func NewProductUseCase(
 	menuRepo repository.MenuRepository,
 	auditRepo repository.AuditRepository,
-	queuePub service.QueuePublisher,
+	outboxRepo repository.OutboxRepository,
 ) *ProductUseCase {
 	return &ProductUseCase{
-		menuRepo:  menuRepo,
-		auditRepo: auditRepo,
-		queuePub:  queuePub,
+		menuRepo:   menuRepo,
+		auditRepo:  auditRepo,
+		outboxRepo: outboxRepo,
 	}
 }
 
-// UpdateProductStatus queues a product status update
+// UpdateProductStatus records a product status update in the outbox; the
+// outbox relay publishes it to the queue
 func (uc *ProductUseCase) UpdateProductStatus(ctx context.Context, restaurantID, productID, newStatus, reason, userID string) error {
 	// Get current status
 	oldStatus, err := uc.menuRepo.GetProductStatus(ctx, restaurantID, productID)
@@ -38,8 +41,9 @@ func (uc *ProductUseCase) UpdateProductStatus(ctx context.Context, restaurantID,
 		return fmt.Errorf("failed to get product status: %w", err)
 	}
 
-	// Publish event to queue
+	// Store event in outbox
 	event := &entity.ProductStatusChangeEvent{
+		EventID:      uuid.New().String(),
 		EventType:    entity.EventTypeProductStatusChanged,
 		RestaurantID: restaurantID,
 		ProductID:    productID,
@@ -50,38 +54,57 @@ func (uc *ProductUseCase) UpdateProductStatus(ctx context.Context, restaurantID,
 		UserID:       userID,
 	}
 
-	if err := uc.queuePub.PublishProductStatusEvent(event); err != nil {
+	if err := uc.outboxRepo.Create(ctx, entity.NewProductStatusOutboxMessage(event)); err != nil {
 		return fmt.Errorf("failed to queue event: %w", err)
 	}
 
 	return nil
 }
 
-// ProcessProductStatusEvent processes a product status change event
+// ProcessProductStatusEvent processes a product status change event. An event
+// that was already applied is skipped, so a redelivered event cannot revert a
+// later status change.
 func (uc *ProductUseCase) ProcessProductStatusEvent(ctx context.Context, event *entity.ProductStatusChangeEvent) error {
+	if event.EventID != "" {
+		processed, err := uc.auditRepo.ExistsByCorrelationID(ctx, event.EventID, event.ProductID)
+		if err != nil {
+			return err
+		}
+		if processed {
+			return nil
+		}
+	}
+
 	// Update product status in DB
-	oldStatus, err := uc.menuRepo.UpdateProductStatus(ctx, event.RestaurantID, event.ProductID, event.NewStatus)
+	menuID, previous, err := uc.menuRepo.UpdateProductStatus(ctx, event.RestaurantID, event.ProductID, event.NewStatus)
 	if err != nil {
 		return fmt.Errorf("failed to update product status: %w", err)
 	}
 
 	// Use actual old status from DB
-	if oldStatus != event.OldStatus {
-		event.OldStatus = oldStatus
+	if previous.Status != event.OldStatus {
+		event.OldStatus = previous.Status
 	}
 
 	// Create audit record
 	audit := &entity.ProductStatusAudit{
-		ProductID: event.ProductID,
-		EventType: event.EventType,
-		OldStatus: event.OldStatus,
-		NewStatus: event.NewStatus,
-		Reason:    event.Reason,
-		UserID:    event.UserID,
-		Timestamp: event.Timestamp,
+		RestaurantID:  event.RestaurantID,
+		MenuID:        menuID,
+		ProductID:     event.ProductID,
+		ProductName:   previous.Name,
+		CorrelationID: event.EventID,
+		EventType:     event.EventType,
+		OldStatus:     event.OldStatus,
+		NewStatus:     event.NewStatus,
+		Reason:        event.Reason,
+		UserID:        event.UserID,
+		Timestamp:     event.Timestamp,
 	}
 
 	if err := uc.auditRepo.Create(ctx, audit); err != nil {
+		if errors.Is(err, repository.ErrAuditRecordExists) {
+			return nil
+		}
 		return fmt.Errorf("failed to create audit record: %w", err)
 	}
 
@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"menu-parser/internal/domain/entity"
+	mongorepo "menu-parser/internal/repository"
+	"menu-parser/pkg/config"
+	"menu-parser/pkg/database"
+)
+
+// newTestMongoDB connects to the server in MONGODB_TEST_URI and returns a
+// fresh database that is dropped when the test ends
+func newTestMongoDB(t *testing.T) *database.MongoDB {
+	t.Helper()
+
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI is not set")
+	}
+
+	db, err := database.NewMongoDB(&config.Config{
+		MongoDBURI:      uri,
+		MongoDBDatabase: "menu_parser_test_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:12],
+		// The tests also run against a standalone server
+		MongoDBAllowNoTransactions: true,
+	})
+	if err != nil {
+		t.Fatalf("connect to MongoDB: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		db.Database.Drop(ctx)
+		db.Close(ctx)
+	})
+	return db
+}
+
+func TestProcessProductStatusEventConcurrent(t *testing.T) {
+	db := newTestMongoDB(t)
+	ctx := context.Background()
+
+	menuRepo := mongorepo.NewMenuRepository(db)
+	productUC := NewProductUseCase(menuRepo, mongorepo.NewAuditRepository(db), mongorepo.NewOutboxRepository(db))
+
+	const restaurantID = "restaurant-1"
+	const productCount = 300
+
+	menu := &entity.Menu{RestaurantID: restaurantID, Name: "Меню"}
+	for i := 0; i < productCount; i++ {
+		menu.Products = append(menu.Products, entity.Product{
+			ExtID:  fmt.Sprintf("p%03d", i),
+			Name:   fmt.Sprintf("Товар %d", i),
+			Status: string(entity.ProductStatusAvailable),
+		})
+	}
+	saved, err := menuRepo.Create(ctx, menu)
+	if err != nil {
+		t.Fatalf("create menu: %v", err)
+	}
+	if _, err := menuRepo.Activate(ctx, restaurantID, saved.ID.Hex()); err != nil {
+		t.Fatalf("activate menu: %v", err)
+	}
+
+	wantStatus := func(i int) string {
+		if i%2 == 0 {
+			return string(entity.ProductStatusNotAvailable)
+		}
+		return string(entity.ProductStatusDeleted)
+	}
+
+	start := make(chan struct{})
+	errs := make(chan error, productCount)
+	var wg sync.WaitGroup
+	for i := 0; i < productCount; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs <- productUC.ProcessProductStatusEvent(ctx, &entity.ProductStatusChangeEvent{
+				EventID:      uuid.New().String(),
+				EventType:    entity.EventTypeProductStatusChanged,
+				RestaurantID: restaurantID,
+				ProductID:    fmt.Sprintf("p%03d", i),
+				OldStatus:    string(entity.ProductStatusAvailable),
+				NewStatus:    wantStatus(i),
+				Reason:       "test",
+				UserID:       "tester",
+				Timestamp:    time.Now(),
+			})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("process event: %v", err)
+		}
+	}
+
+	active, err := menuRepo.GetActiveByRestaurant(ctx, restaurantID)
+	if err != nil {
+		t.Fatalf("get active menu: %v", err)
+	}
+	if len(active.Products) != productCount {
+		t.Fatalf("menu has %d products, want %d", len(active.Products), productCount)
+	}
+	for i, product := range active.Products {
+		if product.Status != wantStatus(i) {
+			t.Errorf("product %s has status %q, want %q", product.ExtID, product.Status, wantStatus(i))
+		}
+	}
+
+	cursor, err := db.Database.Collection("product_status_audit").Find(ctx, bson.M{"restaurant_id": restaurantID})
+	if err != nil {
+		t.Fatalf("find audit records: %v", err)
+	}
+	var audits []entity.ProductStatusAudit
+	if err := cursor.All(ctx, &audits); err != nil {
+		t.Fatalf("decode audit records: %v", err)
+	}
+
+	byProduct := make(map[string][]entity.ProductStatusAudit, len(audits))
+	for _, audit := range audits {
+		byProduct[audit.ProductID] = append(byProduct[audit.ProductID], audit)
+	}
+	for i := 0; i < productCount; i++ {
+		productID := fmt.Sprintf("p%03d", i)
+		records := byProduct[productID]
+		if len(records) != 1 {
+			t.Errorf("product %s has %d audit records, want 1", productID, len(records))
+			continue
+		}
+		audit := records[0]
+		if audit.MenuID != saved.ID || audit.OldStatus != string(entity.ProductStatusAvailable) || audit.NewStatus != wantStatus(i) {
+			t.Errorf("product %s audit: menu %s, %q -> %q; want menu %s, %q -> %q", productID,
+				audit.MenuID.Hex(), audit.OldStatus, audit.NewStatus, saved.ID.Hex(), entity.ProductStatusAvailable, wantStatus(i))
+		}
+	}
+}
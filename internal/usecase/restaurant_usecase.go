@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"menu-parser/internal/domain/apperror"
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+)
+
+const defaultRestaurantTimezone = "UTC"
+
+// ErrInvalidRestaurant is wrapped by validation errors of restaurant input
+var ErrInvalidRestaurant = apperror.New(apperror.KindInvalidArgument, "invalid_restaurant", "invalid restaurant")
+
+// RestaurantUseCase handles restaurant-related business logic
+type RestaurantUseCase struct {
+	restaurantRepo repository.RestaurantRepository
+}
+
+// NewRestaurantUseCase creates a new RestaurantUseCase
+func NewRestaurantUseCase(restaurantRepo repository.RestaurantRepository) *RestaurantUseCase {
+	return &RestaurantUseCase{
+		restaurantRepo: restaurantRepo,
+	}
+}
+
+// CreateRestaurant registers a new restaurant with a generated stable ID
+func (uc *RestaurantUseCase) CreateRestaurant(ctx context.Context, restaurant *entity.Restaurant) (*entity.Restaurant, error) {
+	if err := normalizeRestaurant(restaurant); err != nil {
+		return nil, err
+	}
+	restaurant.ID = uuid.New().String()
+
+	if err := uc.restaurantRepo.Create(ctx, restaurant); err != nil {
+		return nil, err
+	}
+	return restaurant, nil
+}
+
+// GetRestaurant retrieves a restaurant by ID
+func (uc *RestaurantUseCase) GetRestaurant(ctx context.Context, restaurantID string) (*entity.Restaurant, error) {
+	return uc.restaurantRepo.GetByID(ctx, restaurantID)
+}
+
+// ListRestaurants lists all restaurants ordered by name
+func (uc *RestaurantUseCase) ListRestaurants(ctx context.Context) ([]entity.Restaurant, error) {
+	return uc.restaurantRepo.List(ctx)
+}
+
+// UpdateRestaurant replaces the mutable fields of a restaurant
+func (uc *RestaurantUseCase) UpdateRestaurant(ctx context.Context, restaurant *entity.Restaurant) (*entity.Restaurant, error) {
+	if err := normalizeRestaurant(restaurant); err != nil {
+		return nil, err
+	}
+
+	if err := uc.restaurantRepo.Update(ctx, restaurant); err != nil {
+		return nil, err
+	}
+	return uc.restaurantRepo.GetByID(ctx, restaurant.ID)
+}
+
+// DeleteRestaurant removes a restaurant; its menus and audit history are kept
+func (uc *RestaurantUseCase) DeleteRestaurant(ctx context.Context, restaurantID string) error {
+	return uc.restaurantRepo.Delete(ctx, restaurantID)
+}
+
+func normalizeRestaurant(restaurant *entity.Restaurant) error {
+	restaurant.Name = strings.Join(strings.Fields(restaurant.Name), " ")
+	if restaurant.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRestaurant)
+	}
+
+	if restaurant.Timezone == "" {
+		restaurant.Timezone = defaultRestaurantTimezone
+	}
+	if _, err := time.LoadLocation(restaurant.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidRestaurant, restaurant.Timezone)
+	}
+
+	return nil
+}
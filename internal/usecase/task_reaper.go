@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+)
+
+const (
+	taskReaperBatchSize = 100
+	// taskStuckErrorCode marks tasks the reaper failed
+	taskStuckErrorCode = "task_stuck"
+)
+
+var (
+	tasksRequeued = expvar.NewInt("task_reaper_requeued_total")
+	tasksFailed   = expvar.NewInt("task_reaper_failed_total")
+)
+
+// TaskReaper recovers parsing tasks left queued or processing, e.g. by a worker
+// that crashed mid-parse or a message that never reached the queue. A stuck
+// task is queued again through the outbox while it has retries left and
+// marked failed otherwise.
+type TaskReaper struct {
+	taskRepo   repository.TaskRepository
+	outboxRepo repository.OutboxRepository
+	transactor repository.Transactor
+	staleAfter time.Duration
+	interval   time.Duration
+	maxRetries int
+}
+
+// NewTaskReaper creates a new TaskReaper
+func NewTaskReaper(
+	taskRepo repository.TaskRepository,
+	outboxRepo repository.OutboxRepository,
+	transactor repository.Transactor,
+	staleAfter time.Duration,
+	interval time.Duration,
+	maxRetries int,
+) *TaskReaper {
+	return &TaskReaper{
+		taskRepo:   taskRepo,
+		outboxRepo: outboxRepo,
+		transactor: transactor,
+		staleAfter: staleAfter,
+		interval:   interval,
+		maxRetries: maxRetries,
+	}
+}
+
+// Run checks for stuck tasks every interval until ctx is cancelled
+func (r *TaskReaper) Run(ctx context.Context) {
+	log.Printf("Task reaper started (stale after %s)", r.staleAfter)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Task reaper stopped")
+			return
+		case <-ticker.C:
+			if err := r.reap(ctx); err != nil {
+				log.Printf("Error reaping stuck tasks: %v", err)
+			}
+		}
+	}
+}
+
+func (r *TaskReaper) reap(ctx context.Context) error {
+	tasks, err := r.taskRepo.FindStale(ctx, time.Now().Add(-r.staleAfter), taskReaperBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		if task.RetryCount >= r.maxRetries {
+			r.fail(ctx, task)
+		} else {
+			r.requeue(ctx, task)
+		}
+	}
+
+	return nil
+}
+
+func (r *TaskReaper) requeue(ctx context.Context, task *entity.ParsingTask) {
+	var requeued bool
+	err := r.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		requeued, err = r.taskRepo.RequeueStale(ctx, task)
+		if err != nil || !requeued {
+			return err
+		}
+		return r.outboxRepo.Create(ctx, entity.NewMenuParsingOutboxMessage(task.ID))
+	})
+	if err != nil {
+		log.Printf("Error requeueing stuck task %s: %v", task.ID, err)
+		return
+	}
+	if requeued {
+		tasksRequeued.Add(1)
+		log.Printf("Requeued task %s stuck in %s since %s", task.ID, task.Status, task.UpdatedAt.Format(time.RFC3339))
+	}
+}
+
+func (r *TaskReaper) fail(ctx context.Context, task *entity.ParsingTask) {
+	reason := fmt.Sprintf("task stuck in %s for more than %s and no retries left", task.Status, r.staleAfter)
+	var failed bool
+	err := r.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		failed, err = r.taskRepo.FailStale(ctx, task, taskStuckErrorCode, reason)
+		if err != nil || !failed || task.CallbackURL == "" {
+			return err
+		}
+		finished := *task
+		finished.Status = entity.TaskStatusFailed
+		finished.ErrorCode = taskStuckErrorCode
+		finished.ErrorMessage = reason
+		return r.outboxRepo.Create(ctx, entity.NewTaskWebhookOutboxMessage(&finished))
+	})
+	if err != nil {
+		log.Printf("Error failing stuck task %s: %v", task.ID, err)
+		return
+	}
+	if failed {
+		tasksFailed.Add(1)
+		log.Printf("Failed task %s: %s", task.ID, reason)
+	}
+}
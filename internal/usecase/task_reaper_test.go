@@ -0,0 +1,221 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/repository"
+	mongorepo "menu-parser/internal/repository"
+)
+
+const (
+	testStaleAfter = 10 * time.Minute
+	testMaxRetries = 3
+)
+
+// staleTaskRepo serves FindStale from tasks and applies RequeueStale and
+// FailStale to them; other methods panic through the embedded interface
+type staleTaskRepo struct {
+	repository.TaskRepository
+	tasks map[string]*entity.ParsingTask
+}
+
+func (r *staleTaskRepo) FindStale(ctx context.Context, updatedBefore time.Time, limit int) ([]entity.ParsingTask, error) {
+	var stale []entity.ParsingTask
+	for _, task := range r.tasks {
+		active := task.Status == entity.TaskStatusQueued || task.Status == entity.TaskStatusProcessing
+		if active && task.UpdatedAt.Before(updatedBefore) && len(stale) < limit {
+			stale = append(stale, *task)
+		}
+	}
+	return stale, nil
+}
+
+func (r *staleTaskRepo) RequeueStale(ctx context.Context, task *entity.ParsingTask) (bool, error) {
+	stored := r.tasks[task.ID]
+	if stored.Status != task.Status || !stored.UpdatedAt.Equal(task.UpdatedAt) {
+		return false, nil
+	}
+	stored.Status = entity.TaskStatusQueued
+	stored.RetryCount++
+	stored.UpdatedAt = time.Now()
+	return true, nil
+}
+
+func (r *staleTaskRepo) FailStale(ctx context.Context, task *entity.ParsingTask, errorCode, reason string) (bool, error) {
+	stored := r.tasks[task.ID]
+	if stored.Status != task.Status || !stored.UpdatedAt.Equal(task.UpdatedAt) {
+		return false, nil
+	}
+	stored.Status = entity.TaskStatusFailed
+	stored.ErrorCode = errorCode
+	stored.ErrorMessage = reason
+	stored.UpdatedAt = time.Now()
+	return true, nil
+}
+
+type recordingOutboxRepo struct {
+	repository.OutboxRepository
+	messages []*entity.OutboxMessage
+}
+
+func (r *recordingOutboxRepo) Create(ctx context.Context, message *entity.OutboxMessage) error {
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+type noTransactor struct{}
+
+func (noTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func TestTaskReaperReap(t *testing.T) {
+	stale := time.Now().Add(-2 * testStaleAfter)
+
+	tests := []struct {
+		name        string
+		task        entity.ParsingTask
+		wantStatus  entity.ParsingTaskStatus
+		wantRetries int
+		wantCode    string
+		// wantMessage is the type of the outbox message written, if any
+		wantMessage entity.OutboxMessageType
+	}{
+		{
+			name:        "processing with retries left",
+			task:        entity.ParsingTask{Status: entity.TaskStatusProcessing, RetryCount: 1, UpdatedAt: stale},
+			wantStatus:  entity.TaskStatusQueued,
+			wantRetries: 2,
+			wantMessage: entity.OutboxMessageMenuParsingTask,
+		},
+		{
+			name:        "queued never published",
+			task:        entity.ParsingTask{Status: entity.TaskStatusQueued, UpdatedAt: stale},
+			wantStatus:  entity.TaskStatusQueued,
+			wantRetries: 1,
+			wantMessage: entity.OutboxMessageMenuParsingTask,
+		},
+		{
+			name:        "last retry used",
+			task:        entity.ParsingTask{Status: entity.TaskStatusProcessing, RetryCount: testMaxRetries, UpdatedAt: stale},
+			wantStatus:  entity.TaskStatusFailed,
+			wantRetries: testMaxRetries,
+			wantCode:    taskStuckErrorCode,
+		},
+		{
+			name: "last retry used with a callback",
+			task: entity.ParsingTask{
+				Status:      entity.TaskStatusQueued,
+				RetryCount:  testMaxRetries,
+				CallbackURL: "https://example.com/hook",
+				UpdatedAt:   stale,
+			},
+			wantStatus:  entity.TaskStatusFailed,
+			wantRetries: testMaxRetries,
+			wantCode:    taskStuckErrorCode,
+			wantMessage: entity.OutboxMessageTaskWebhook,
+		},
+		{
+			name:       "recently updated",
+			task:       entity.ParsingTask{Status: entity.TaskStatusProcessing, UpdatedAt: time.Now()},
+			wantStatus: entity.TaskStatusProcessing,
+		},
+		{
+			name:        "already failed",
+			task:        entity.ParsingTask{Status: entity.TaskStatusFailed, RetryCount: testMaxRetries, UpdatedAt: stale},
+			wantStatus:  entity.TaskStatusFailed,
+			wantRetries: testMaxRetries,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.task
+			task.ID = "task-1"
+			tasks := &staleTaskRepo{tasks: map[string]*entity.ParsingTask{task.ID: &task}}
+			outbox := &recordingOutboxRepo{}
+			reaper := NewTaskReaper(tasks, outbox, noTransactor{}, testStaleAfter, time.Minute, testMaxRetries)
+
+			if err := reaper.reap(context.Background()); err != nil {
+				t.Fatalf("reap: %v", err)
+			}
+
+			if task.Status != tt.wantStatus || task.RetryCount != tt.wantRetries || task.ErrorCode != tt.wantCode {
+				t.Errorf("task status %s, retries %d, error code %q; want %s, %d, %q",
+					task.Status, task.RetryCount, task.ErrorCode, tt.wantStatus, tt.wantRetries, tt.wantCode)
+			}
+			if tt.wantMessage == "" {
+				if len(outbox.messages) != 0 {
+					t.Errorf("outbox messages = %d, want none", len(outbox.messages))
+				}
+				return
+			}
+			if len(outbox.messages) != 1 || outbox.messages[0].Type != tt.wantMessage {
+				t.Fatalf("outbox messages = %+v, want one %s", outbox.messages, tt.wantMessage)
+			}
+			if webhook := outbox.messages[0].Webhook; webhook != nil && webhook.Payload.ErrorCode != taskStuckErrorCode {
+				t.Errorf("webhook error code = %q, want %q", webhook.Payload.ErrorCode, taskStuckErrorCode)
+			}
+		})
+	}
+}
+
+func TestTaskReaperReapMongo(t *testing.T) {
+	db := newTestMongoDB(t)
+	ctx := context.Background()
+
+	taskRepo := mongorepo.NewTaskRepository(db)
+	reaper := NewTaskReaper(taskRepo, mongorepo.NewOutboxRepository(db), mongorepo.NewTransactor(db), testStaleAfter, time.Minute, testMaxRetries)
+
+	stale := time.Now().Add(-2 * testStaleAfter)
+	for _, task := range []*entity.ParsingTask{
+		{ID: "retry", Status: entity.TaskStatusProcessing, RetryCount: 1, UpdatedAt: stale},
+		{ID: "exhausted", Status: entity.TaskStatusQueued, RetryCount: testMaxRetries, UpdatedAt: stale},
+		{ID: "fresh", Status: entity.TaskStatusProcessing, UpdatedAt: time.Now()},
+		{ID: "done", Status: entity.TaskStatusCompleted, UpdatedAt: stale},
+	} {
+		if err := taskRepo.Create(ctx, task); err != nil {
+			t.Fatalf("create task %s: %v", task.ID, err)
+		}
+	}
+
+	if err := reaper.reap(ctx); err != nil {
+		t.Fatalf("reap: %v", err)
+	}
+
+	want := map[string]struct {
+		status  entity.ParsingTaskStatus
+		retries int
+		code    string
+	}{
+		"retry":     {entity.TaskStatusQueued, 2, ""},
+		"exhausted": {entity.TaskStatusFailed, testMaxRetries, taskStuckErrorCode},
+		"fresh":     {entity.TaskStatusProcessing, 0, ""},
+		"done":      {entity.TaskStatusCompleted, 0, ""},
+	}
+	for id, w := range want {
+		task, err := taskRepo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("get task %s: %v", id, err)
+		}
+		if task.Status != w.status || task.RetryCount != w.retries || task.ErrorCode != w.code {
+			t.Errorf("task %s: status %s, retries %d, error code %q; want %s, %d, %q",
+				id, task.Status, task.RetryCount, task.ErrorCode, w.status, w.retries, w.code)
+		}
+	}
+
+	published, err := db.Database.Collection("outbox").CountDocuments(ctx, bson.M{
+		"type":    entity.OutboxMessageMenuParsingTask,
+		"task_id": "retry",
+	})
+	if err != nil {
+		t.Fatalf("count outbox messages: %v", err)
+	}
+	if published != 1 {
+		t.Errorf("outbox messages for the requeued task = %d, want 1", published)
+	}
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"menu-parser/internal/domain/apperror"
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/service"
+)
+
+var (
+	ErrInvalidAPIKey = apperror.New(apperror.KindUnauthenticated, "invalid_api_key", "invalid API key")
+	ErrInvalidToken  = apperror.New(apperror.KindUnauthenticated, "invalid_token", "invalid token")
+)
+
+// Authenticator checks API keys from configuration and HS256 JWTs signed
+// with a shared secret
+type Authenticator struct {
+	// API keys are looked up by their SHA-256, so the lookup time does not
+	// depend on how much of a key matches
+	apiKeys   map[[sha256.Size]byte]entity.Principal
+	jwtSecret []byte
+}
+
+// NewAuthenticator parses the API key list and keeps the JWT secret; an empty
+// secret disables JWT authentication
+func NewAuthenticator(apiKeys, jwtSecret string) (service.Authenticator, error) {
+	keys, err := parseAPIKeys(apiKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authenticator{
+		apiKeys:   keys,
+		jwtSecret: []byte(jwtSecret),
+	}, nil
+}
+
+// parseAPIKeys parses a comma-separated list of <key>=<subject>:<role>[:<restaurant>|<restaurant>...],
+// e.g. "k1=ops-bot:operator:rest-1|rest-2,k2=alice:admin"
+func parseAPIKeys(spec string) (map[[sha256.Size]byte]entity.Principal, error) {
+	keys := make(map[[sha256.Size]byte]entity.Principal)
+	if strings.TrimSpace(spec) == "" {
+		return keys, nil
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		// Keys may end with base64 padding, the rest of the entry has no '='
+		sep := strings.LastIndex(entry, "=")
+		if sep <= 0 {
+			return nil, fmt.Errorf("invalid API key entry: missing key")
+		}
+		key, value := entry[:sep], entry[sep+1:]
+
+		parts := strings.Split(value, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid API key entry for %q: expected subject:role[:restaurants]", value)
+		}
+
+		principal := entity.Principal{Subject: parts[0], Role: entity.Role(parts[1])}
+		if !principal.Role.Valid() {
+			return nil, fmt.Errorf("invalid role %q for API key of %s", parts[1], principal.Subject)
+		}
+		if len(parts) == 3 && parts[2] != "" {
+			principal.RestaurantIDs = strings.Split(parts[2], "|")
+		}
+
+		keys[sha256.Sum256([]byte(key))] = principal
+	}
+
+	return keys, nil
+}
+
+func (a *Authenticator) AuthenticateAPIKey(apiKey string) (*entity.Principal, error) {
+	principal, ok := a.apiKeys[sha256.Sum256([]byte(apiKey))]
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	return &principal, nil
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+}
+
+type tokenClaims struct {
+	Subject     string   `json:"sub"`
+	Role        string   `json:"role"`
+	Restaurants []string `json:"restaurants"`
+	ExpiresAt   int64    `json:"exp"`
+	NotBefore   int64    `json:"nbf"`
+}
+
+// AuthenticateToken verifies an HS256 JWT with sub, role, restaurants and exp claims
+func (a *Authenticator) AuthenticateToken(token string) (*entity.Principal, error) {
+	if len(a.jwtSecret) == 0 {
+		return nil, fmt.Errorf("%w: JWT authentication is not configured", ErrInvalidToken)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	// Only the configured algorithm is accepted, never "none" or one chosen by the token
+	if header.Alg != "HS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+
+	now := time.Now().Unix()
+	if claims.ExpiresAt == 0 || now >= claims.ExpiresAt {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if claims.NotBefore != 0 && now < claims.NotBefore {
+		return nil, fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+
+	principal := &entity.Principal{
+		Subject:       claims.Subject,
+		Role:          entity.Role(claims.Role),
+		RestaurantIDs: claims.Restaurants,
+	}
+	if principal.Subject == "" || !principal.Role.Valid() {
+		return nil, fmt.Errorf("%w: sub and a valid role are required", ErrInvalidToken)
+	}
+
+	return principal, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/service"
+)
+
+const testJWTSecret = "jwt-secret"
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal token segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signToken builds a JWT with the given header and claims, signed with secret
+func signToken(t *testing.T, secret string, header, claims map[string]interface{}) string {
+	t.Helper()
+	unsigned := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newTestAuthenticator(t *testing.T, apiKeys, jwtSecret string) service.Authenticator {
+	t.Helper()
+	authenticator, err := NewAuthenticator(apiKeys, jwtSecret)
+	if err != nil {
+		t.Fatalf("new authenticator: %v", err)
+	}
+	return authenticator
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	now := time.Now()
+	hs256 := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub":         "alice",
+			"role":        "operator",
+			"restaurants": []string{"rest-1"},
+			"exp":         now.Add(time.Hour).Unix(),
+		}
+		for k, v := range changes {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+	valid := signToken(t, testJWTSecret, hs256, claims(nil))
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"valid", valid, true},
+		{"nbf in the past", signToken(t, testJWTSecret, hs256, claims(map[string]interface{}{"nbf": now.Add(-time.Minute).Unix()})), true},
+		{"alg none", encodeSegment(t, map[string]string{"alg": "none"}) + "." + encodeSegment(t, claims(nil)) + ".", false},
+		{"alg none signed", signToken(t, testJWTSecret, map[string]interface{}{"alg": "none"}, claims(nil)), false},
+		{"alg HS512", signToken(t, testJWTSecret, map[string]interface{}{"alg": "HS512"}, claims(nil)), false},
+		{"alg RS256", signToken(t, testJWTSecret, map[string]interface{}{"alg": "RS256"}, claims(nil)), false},
+		{"alg lowercase", signToken(t, testJWTSecret, map[string]interface{}{"alg": "hs256"}, claims(nil)), false},
+		{"other secret", signToken(t, "other-secret", hs256, claims(nil)), false},
+		{"tampered signature", valid[:len(valid)-43] + flipFirst(valid[len(valid)-43:]), false},
+		{"expired", signToken(t, testJWTSecret, hs256, claims(map[string]interface{}{"exp": now.Add(-time.Second).Unix()})), false},
+		{"without exp", signToken(t, testJWTSecret, hs256, claims(map[string]interface{}{"exp": nil})), false},
+		{"used before nbf", signToken(t, testJWTSecret, hs256, claims(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()})), false},
+		{"unknown role", signToken(t, testJWTSecret, hs256, claims(map[string]interface{}{"role": "superuser"})), false},
+		{"without sub", signToken(t, testJWTSecret, hs256, claims(map[string]interface{}{"sub": nil})), false},
+		{"two segments", "a.b", false},
+		{"four segments", valid + ".x", false},
+		{"empty", "", false},
+		{"header not base64", "!!!." + valid[len(encodeSegment(t, hs256))+1:], false},
+		{"header not JSON", base64.RawURLEncoding.EncodeToString([]byte("alg")) + valid[len(encodeSegment(t, hs256)):], false},
+		{"signature not base64", valid[:len(valid)-43] + "!!!", false},
+	}
+
+	authenticator := newTestAuthenticator(t, "", testJWTSecret)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authenticator.AuthenticateToken(tt.token)
+			if !tt.ok {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("error = %v, want %v", err, ErrInvalidToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("authenticate: %v", err)
+			}
+			if principal.Subject != "alice" || principal.Role != entity.RoleOperator || !slices.Equal(principal.RestaurantIDs, []string{"rest-1"}) {
+				t.Errorf("principal = %+v", principal)
+			}
+		})
+	}
+}
+
+// flipFirst changes the first character of a base64url segment, which unlike
+// the last one carries no padding bits
+func flipFirst(s string) string {
+	if s[0] == 'A' {
+		return "B" + s[1:]
+	}
+	return "A" + s[1:]
+}
+
+func TestAuthenticateTokenTamperedClaims(t *testing.T) {
+	authenticator := newTestAuthenticator(t, "", testJWTSecret)
+	header := map[string]interface{}{"alg": "HS256"}
+	exp := time.Now().Add(time.Hour).Unix()
+	token := signToken(t, testJWTSecret, header, map[string]interface{}{"sub": "bob", "role": "readonly", "exp": exp})
+	forged := signToken(t, testJWTSecret, header, map[string]interface{}{"sub": "bob", "role": "admin", "exp": exp})
+
+	// The admin claims with the signature of the read-only ones
+	tampered := forged[:len(forged)-43] + token[len(token)-43:]
+	if _, err := authenticator.AuthenticateToken(tampered); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestAuthenticateTokenWithoutSecret(t *testing.T) {
+	authenticator := newTestAuthenticator(t, "", "")
+	// Signed with the empty secret the authenticator would otherwise use
+	token := signToken(t, "", map[string]interface{}{"alg": "HS256"},
+		map[string]interface{}{"sub": "alice", "role": "admin", "exp": time.Now().Add(time.Hour).Unix()})
+	if _, err := authenticator.AuthenticateToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		ok   bool
+	}{
+		{"empty", "", true},
+		{"blank", "  ", true},
+		{"admin", "k1=alice:admin", true},
+		{"scoped operator", "k1=bot:operator:rest-1|rest-2", true},
+		{"several", "k1=alice:admin, k2=bob:readonly:*", true},
+		{"base64 padded key", "a2V5==bob:readonly", true},
+		{"unknown role", "k1=alice:owner", false},
+		{"empty role", "k1=alice:", false},
+		{"without role", "k1=alice", false},
+		{"without subject", "k1=:admin", false},
+		{"without key", "=alice:admin", false},
+		{"without separator", "alice:admin", false},
+		{"too many parts", "k1=alice:operator:rest-1:extra", false},
+		{"trailing comma", "k1=alice:admin,", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseAPIKeys(tt.spec)
+			if ok := err == nil; ok != tt.ok {
+				t.Errorf("parseAPIKeys(%q) error = %v, want ok %v", tt.spec, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	authenticator := newTestAuthenticator(t, "k1=bot:operator:rest-1|rest-2,a2V5==bob:readonly", "")
+
+	principal, err := authenticator.AuthenticateAPIKey("k1")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if principal.Subject != "bot" || principal.Role != entity.RoleOperator || !slices.Equal(principal.RestaurantIDs, []string{"rest-1", "rest-2"}) {
+		t.Errorf("principal = %+v", principal)
+	}
+
+	if principal, err := authenticator.AuthenticateAPIKey("a2V5="); err != nil || principal.Subject != "bob" {
+		t.Errorf("padded key: principal %+v, error %v", principal, err)
+	}
+
+	for _, key := range []string{"", "k2", "k1 ", "K1", "bot"} {
+		if _, err := authenticator.AuthenticateAPIKey(key); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("key %q: error = %v, want %v", key, err, ErrInvalidAPIKey)
+		}
+	}
+}
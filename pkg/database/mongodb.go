@@ -2,18 +2,29 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"menu-parser/pkg/config"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Server error codes of a missing index and a missing collection
+const (
+	indexNotFoundCode     = 27
+	namespaceNotFoundCode = 26
+)
+
 type MongoDB struct {
 	Client   *mongo.Client
 	Database *mongo.Database
+	// SupportsTransactions is true when connected to a replica set or a sharded cluster
+	SupportsTransactions bool
 }
 
 func NewMongoDB(cfg *config.Config) (*MongoDB, error) {
@@ -35,6 +46,21 @@ func NewMongoDB(cfg *config.Config) (*MongoDB, error) {
 		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
 	}
 
+	supportsTransactions, err := supportsTransactions(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MongoDB topology: %w", err)
+	}
+	if !supportsTransactions {
+		// Without transactions a failure between the writes of a task and its
+		// outbox message, or of a menu and its audit records, is not rolled back
+		if !cfg.MongoDBAllowNoTransactions {
+			return nil, errors.New("MongoDB is a standalone server without transactions; " +
+				"run a replica set or set MONGODB_ALLOW_NO_TRANSACTIONS=true")
+		}
+		log.Println("WARNING: MongoDB is a standalone server, writes run without transactions " +
+			"and a partial failure is not rolled back (MONGODB_ALLOW_NO_TRANSACTIONS=true)")
+	}
+
 	db := client.Database(cfg.MongoDBDatabase)
 
 	// Create indexes
@@ -43,11 +69,23 @@ func NewMongoDB(cfg *config.Config) (*MongoDB, error) {
 	}
 
 	return &MongoDB{
-		Client:   client,
-		Database: db,
+		Client:               client,
+		Database:             db,
+		SupportsTransactions: supportsTransactions,
 	}, nil
 }
 
+func supportsTransactions(ctx context.Context, client *mongo.Client) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false, err
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
+
 func createIndexes(ctx context.Context, db *mongo.Database) error {
 	// Indexes for menus collection
 	menusCollection := db.Collection("menus")
@@ -58,6 +96,12 @@ func createIndexes(ctx context.Context, db *mongo.Database) error {
 		{
 			Keys: map[string]interface{}{"products.ext_id": 1},
 		},
+		{
+			Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "version", Value: -1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"version": bson.M{"$gt": 0}}),
+		},
 	}
 	if _, err := menusCollection.Indexes().CreateMany(ctx, menusIndexes); err != nil {
 		return fmt.Errorf("failed to create menus indexes: %w", err)
@@ -66,19 +110,37 @@ func createIndexes(ctx context.Context, db *mongo.Database) error {
 	// Indexes for parsing_tasks collection
 	tasksCollection := db.Collection("parsing_tasks")
 	tasksIndexes := []mongo.IndexModel{
+		// Task listings sort by created_at or updated_at with _id breaking
+		// ties. Status filters get an index per sort field, which also serves
+		// the reaper's lookup of stale tasks; a restaurant's tasks are few
+		// enough to sort by updated_at in memory. Tasks without a registered
+		// restaurant are matched by restaurant_name.
+		{
+			Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+		},
 		{
-			Keys: map[string]interface{}{"_id": 1},
+			Keys: bson.D{{Key: "updated_at", Value: 1}, {Key: "_id", Value: 1}},
 		},
 		{
-			Keys: map[string]interface{}{"status": 1},
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
 		},
 		{
-			Keys: map[string]interface{}{"created_at": 1},
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}, {Key: "_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "restaurant_name", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
 		},
 	}
 	if _, err := tasksCollection.Indexes().CreateMany(ctx, tasksIndexes); err != nil {
 		return fmt.Errorf("failed to create parsing_tasks indexes: %w", err)
 	}
+	// Superseded by the compound indexes above
+	if err := dropIndexes(ctx, tasksCollection, "status_1", "created_at_1"); err != nil {
+		return fmt.Errorf("failed to drop parsing_tasks indexes: %w", err)
+	}
 
 	// Indexes for product_status_audit collection
 	auditCollection := db.Collection("product_status_audit")
@@ -89,11 +151,81 @@ func createIndexes(ctx context.Context, db *mongo.Database) error {
 		{
 			Keys: map[string]interface{}{"timestamp": 1},
 		},
+		{
+			Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "product_id", Value: 1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: map[string]interface{}{"menu_id": 1},
+		},
+		{
+			// Also makes redelivered status events idempotent: one record per event and product
+			Keys: bson.D{{Key: "correlation_id", Value: 1}, {Key: "product_id", Value: 1}, {Key: "event_type", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"correlation_id": bson.M{"$exists": true}}),
+		},
 	}
 	if _, err := auditCollection.Indexes().CreateMany(ctx, auditIndexes); err != nil {
 		return fmt.Errorf("failed to create product_status_audit indexes: %w", err)
 	}
 
+	// Indexes for outbox collection
+	outboxCollection := db.Collection("outbox")
+	outboxIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "available_at", Value: 1}},
+		},
+		{
+			// Published messages are kept for a week for troubleshooting
+			Keys:    map[string]interface{}{"published_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(7 * 24 * 60 * 60),
+		},
+	}
+	if _, err := outboxCollection.Indexes().CreateMany(ctx, outboxIndexes); err != nil {
+		return fmt.Errorf("failed to create outbox indexes: %w", err)
+	}
+
+	// Indexes for dlq_audit collection
+	dlqAuditCollection := db.Collection("dlq_audit")
+	dlqAuditIndexes := []mongo.IndexModel{
+		{
+			Keys: map[string]interface{}{"timestamp": -1},
+		},
+		{
+			Keys: map[string]interface{}{"message_id": 1},
+		},
+	}
+	if _, err := dlqAuditCollection.Indexes().CreateMany(ctx, dlqAuditIndexes); err != nil {
+		return fmt.Errorf("failed to create dlq_audit indexes: %w", err)
+	}
+
+	// Indexes for restaurants collection
+	restaurantsCollection := db.Collection("restaurants")
+	restaurantsIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"name_key": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := restaurantsCollection.Indexes().CreateMany(ctx, restaurantsIndexes); err != nil {
+		return fmt.Errorf("failed to create restaurants indexes: %w", err)
+	}
+
+	// Indexes for column_mappings collection
+	mappingsCollection := db.Collection("column_mappings")
+	mappingsIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "restaurant_id", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := mappingsCollection.Indexes().CreateMany(ctx, mappingsIndexes); err != nil {
+		return fmt.Errorf("failed to create column_mappings indexes: %w", err)
+	}
+
 	return nil
 }
 
@@ -107,4 +239,17 @@ func (m *MongoDB) HealthCheck(ctx context.Context) error {
 	return m.Client.Ping(ctx, nil)
 }
 
-
+// dropIndexes removes the named indexes, skipping those that do not exist
+func dropIndexes(ctx context.Context, collection *mongo.Collection, names ...string) error {
+	for _, name := range names {
+		_, err := collection.Indexes().DropOne(ctx, name)
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && (cmdErr.Code == indexNotFoundCode || cmdErr.Code == namespaceNotFoundCode) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("drop index %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -2,20 +2,27 @@ package health
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"menu-parser/internal/usecase"
 	"menu-parser/pkg/database"
 	"menu-parser/pkg/queue"
 )
 
+// QueueChecker is implemented by every queue backend
+type QueueChecker interface {
+	HealthCheck() error
+}
+
 // HealthService implements usecase.HealthCheckService
 type HealthService struct {
 	db    *database.MongoDB
-	queue *queue.RabbitMQ
+	queue QueueChecker
 }
 
 // NewHealthService creates a new health service
-func NewHealthService(db *database.MongoDB, queue *queue.RabbitMQ) usecase.HealthCheckService {
+func NewHealthService(db *database.MongoDB, queue QueueChecker) usecase.HealthCheckService {
 	return &HealthService{
 		db:    db,
 		queue: queue,
@@ -27,7 +34,11 @@ func (s *HealthService) CheckDatabase(ctx context.Context) error {
 }
 
 func (s *HealthService) CheckQueue() error {
-	return s.queue.HealthCheck()
+	err := s.queue.HealthCheck()
+	if errors.Is(err, queue.ErrReconnecting) {
+		return fmt.Errorf("%w: %v", usecase.ErrServiceDegraded, err)
+	}
+	return err
 }
 
 
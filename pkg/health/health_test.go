@@ -0,0 +1,45 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"menu-parser/internal/usecase"
+	"menu-parser/pkg/queue"
+)
+
+type stubQueue struct {
+	err error
+}
+
+func (q stubQueue) HealthCheck() error {
+	return q.err
+}
+
+func TestCheckQueue(t *testing.T) {
+	errDown := errors.New("connection refused")
+
+	tests := []struct {
+		name     string
+		err      error
+		degraded bool
+	}{
+		{"connected", nil, false},
+		{"reconnecting", queue.ErrReconnecting, true},
+		{"reconnecting wrapped", fmt.Errorf("publish: %w", queue.ErrReconnecting), true},
+		{"closed", queue.ErrClosed, false},
+		{"other error", errDown, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewHealthService(nil, stubQueue{err: tt.err}).CheckQueue()
+			if degraded := errors.Is(err, usecase.ErrServiceDegraded); degraded != tt.degraded {
+				t.Errorf("error = %v, degraded %v; want degraded %v", err, degraded, tt.degraded)
+			}
+			if (err == nil) != (tt.err == nil) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"menu-parser/internal/domain/apperror"
+
+	"google.golang.org/api/googleapi"
+)
+
+var (
+	errNoSheets = apperror.New(apperror.KindInvalidArgument, "empty_spreadsheet", "no sheets found in spreadsheet")
+	errNoData   = apperror.New(apperror.KindInvalidArgument, "empty_spreadsheet", "no data found in spreadsheet")
+)
+
+// sheetsAPIError classifies a Sheets API failure: a bad spreadsheet ID, missing
+// access or a missing spreadsheet are permanent, while rate limits, server
+// errors, timeouts and network failures are worth retrying
+func sheetsAPIError(err error, message string) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == http.StatusBadRequest:
+			return apperror.Wrap(err, apperror.KindInvalidArgument, "invalid_spreadsheet", message)
+		case apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusForbidden:
+			return apperror.Wrap(err, apperror.KindPermissionDenied, "spreadsheet_access_denied", message)
+		case apiErr.Code == http.StatusNotFound:
+			return apperror.Wrap(err, apperror.KindNotFound, "spreadsheet_not_found", message)
+		case apiErr.Code == http.StatusTooManyRequests:
+			return apperror.Wrap(err, apperror.KindUnavailable, "sheets_rate_limited", message)
+		case apiErr.Code >= http.StatusInternalServerError:
+			return apperror.Wrap(err, apperror.KindUnavailable, "sheets_unavailable", message)
+		}
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return apperror.Wrap(err, apperror.KindUnavailable, "sheets_timeout", message)
+	}
+	if errors.As(err, &netErr) {
+		return apperror.Wrap(err, apperror.KindUnavailable, "sheets_unavailable", message)
+	}
+
+	return apperror.Wrap(err, apperror.KindInternal, "sheets_error", message)
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"menu-parser/internal/domain/apperror"
 	"menu-parser/internal/domain/entity"
 	"menu-parser/internal/domain/service"
 
@@ -14,6 +15,10 @@ import (
 	"google.golang.org/api/sheets/v4"
 )
 
+// sheetColumns is the column span read from a sheet. It covers every column
+// letter columnLetterIndex accepts, so mapped columns past Z are not cut off.
+const sheetColumns = "A:ZZZ"
+
 type sheetsParser struct {
 	service *sheets.Service
 }
@@ -31,204 +36,395 @@ func NewSheetsParser(credentialsPath string) (service.SheetsParser, error) {
 	}, nil
 }
 
-func (p *sheetsParser) ParseMenu(ctx context.Context, spreadsheetID, restaurantName string) (*entity.Menu, error) {
-	// Get spreadsheet metadata to find the first sheet name
+func (p *sheetsParser) ParseMenu(ctx context.Context, req *service.ParseMenuRequest) (*entity.Menu, error) {
+	spreadsheetID := req.SpreadsheetID
+	restaurantName := req.RestaurantName
+	restaurantID := req.RestaurantID
+	if restaurantID == "" {
+		restaurantID = restaurantName
+	}
+
+	mapping := req.ColumnMapping
+	if mapping == nil {
+		mapping = entity.DefaultColumnMapping()
+	}
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Get spreadsheet metadata to find the sheet names
 	spreadsheet, err := p.service.Spreadsheets.Get(spreadsheetID).Context(ctx).Do()
 	if err != nil {
-		return nil, fmt.Errorf("unable to get spreadsheet metadata: %w", err)
+		return nil, sheetsAPIError(err, "unable to get spreadsheet metadata")
 	}
 
 	if len(spreadsheet.Sheets) == 0 {
-		return nil, fmt.Errorf("no sheets found in spreadsheet")
+		return nil, errNoSheets
+	}
+
+	sheetNames, err := selectSheets(spreadsheet, req)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := newMenuBuilder()
+	for i, sheetName := range sheetNames {
+		// Range fallbacks without a sheet name only make sense for the first sheet
+		values, err := p.readSheet(ctx, spreadsheetID, sheetName, i == 0 && sheetName == spreadsheet.Sheets[0].Properties.Title)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		cols, dataRows, err := resolveColumns(mapping, values)
+		if err != nil {
+			return nil, apperror.New(apperror.KindInvalidArgument, "column_not_found", fmt.Sprintf("sheet %q: %v", sheetName, err))
+		}
+
+		builder.parseSheetData(dataRows, cols, sheetName)
+	}
+
+	if builder.rowsSeen == 0 {
+		return nil, errNoData
+	}
+	if len(builder.duplicateExtIDs) > 0 {
+		return nil, apperror.New(apperror.KindInvalidArgument, "duplicate_product_id",
+			fmt.Sprintf("product IDs used by several rows: %s", strings.Join(builder.duplicateExtIDs, ", ")))
+	}
+
+	menu := &entity.Menu{
+		Name:             restaurantName,
+		RestaurantID:     restaurantID,
+		Products:         []entity.Product{},
+		AttributesGroups: []entity.AttributesGroup{},
+		Attributes:       []entity.Attribute{},
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	menu.Products = builder.products
+	menu.AttributesGroups = builder.attributesGroups
+	menu.Attributes = builder.attributes
+
+	return menu, nil
+}
+
+// selectSheets returns the titles of the sheets to parse: every sheet, the
+// requested ones in request order, or only the first sheet by default
+func selectSheets(spreadsheet *sheets.Spreadsheet, req *service.ParseMenuRequest) ([]string, error) {
+	if req.AllSheets {
+		names := make([]string, 0, len(spreadsheet.Sheets))
+		for _, sheet := range spreadsheet.Sheets {
+			names = append(names, sheet.Properties.Title)
+		}
+		return names, nil
+	}
+
+	if len(req.Sheets) == 0 {
+		return []string{spreadsheet.Sheets[0].Properties.Title}, nil
+	}
+
+	available := make(map[string]bool, len(spreadsheet.Sheets))
+	for _, sheet := range spreadsheet.Sheets {
+		available[sheet.Properties.Title] = true
+	}
+
+	names := make([]string, 0, len(req.Sheets))
+	for _, name := range req.Sheets {
+		if !available[name] {
+			return nil, apperror.New(apperror.KindNotFound, "sheet_not_found", fmt.Sprintf("sheet %q not found in spreadsheet", name))
+		}
+		names = append(names, name)
 	}
+	return names, nil
+}
 
-	// Use the first sheet
-	sheetName := spreadsheet.Sheets[0].Properties.Title
-	
+func (p *sheetsParser) readSheet(ctx context.Context, spreadsheetID, sheetName string, allowFallback bool) ([][]interface{}, error) {
 	// Try different range formats
 	var resp *sheets.ValueRange
 	var readRange string
 	var lastErr error
-	
-	// Try format: "SheetName!A:Z" (if sheet name has spaces, use single quotes)
-	if strings.Contains(sheetName, " ") || strings.Contains(sheetName, "'") {
-		readRange = fmt.Sprintf("'%s'!A:Z", strings.ReplaceAll(sheetName, "'", "''"))
-	} else {
-		readRange = fmt.Sprintf("%s!A:Z", sheetName)
-	}
+	var err error
+
+	// Try format: "'SheetName'!A:ZZZ"
+	readRange = quoteSheetName(sheetName) + "!" + sheetColumns
 	resp, err = p.service.Spreadsheets.Values.Get(spreadsheetID, readRange).Context(ctx).Do()
 	if err != nil {
+		if !allowFallback {
+			return nil, sheetsAPIError(err, fmt.Sprintf("unable to retrieve data from sheet %q", sheetName))
+		}
 		lastErr = err
 		// Try format without sheet name (uses first sheet by default)
-		readRange = "A:Z"
+		readRange = sheetColumns
 		resp, err = p.service.Spreadsheets.Values.Get(spreadsheetID, readRange).Context(ctx).Do()
 		if err != nil {
 			// Try with just the sheet name (gets all data)
-			readRange = sheetName
+			readRange = quoteSheetName(sheetName)
 			resp, err = p.service.Spreadsheets.Values.Get(spreadsheetID, readRange).Context(ctx).Do()
 			if err != nil {
-				return nil, fmt.Errorf("unable to retrieve data from sheet (tried ranges: %s, A:Z, %s): %w (last error: %v)", 
-					fmt.Sprintf("%s!A:Z", sheetName), sheetName, err, lastErr)
+				return nil, sheetsAPIError(err, fmt.Sprintf("unable to retrieve data from sheet (tried ranges: %s!%s, %s, %s, first error: %v)",
+					quoteSheetName(sheetName), sheetColumns, sheetColumns, quoteSheetName(sheetName), lastErr))
 			}
 		}
 	}
 
-	if len(resp.Values) == 0 {
-		return nil, fmt.Errorf("no data found in spreadsheet")
-	}
-
-	menu := &entity.Menu{
-		Name:             restaurantName,
-		RestaurantID:     restaurantName,
-		Products:         []entity.Product{},
-		AttributesGroups: []entity.AttributesGroup{},
-		Attributes:       []entity.Attribute{},
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
-	}
+	return resp.Values, nil
+}
 
-	products, attributesGroups, attributes := p.parseSheetData(resp.Values)
+// quoteSheetName quotes a sheet name for A1 notation. Names are always quoted
+// so that ones looking like cell references ("A1", "R1C1") or containing
+// punctuation are not misread; embedded quotes are doubled.
+func quoteSheetName(name string) string {
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
 
-	menu.Products = products
-	menu.AttributesGroups = attributesGroups
-	menu.Attributes = attributes
+// menuBuilder accumulates products, attributes and attribute groups across
+// sheets so that IDs and de-duplication stay consistent for the whole menu
+type menuBuilder struct {
+	products         []entity.Product
+	attributesGroups []entity.AttributesGroup
+	attributes       []entity.Attribute
+
+	usedExtIDs map[string]bool
+	// duplicateExtIDs lists sheet IDs given to more than one product
+	duplicateExtIDs    []string
+	rowsSeen           int
+	attributeMap       map[string]bool
+	attributesGroupMap map[string]*entity.AttributesGroup
+}
 
-	return menu, nil
+func newMenuBuilder() *menuBuilder {
+	return &menuBuilder{
+		products:           []entity.Product{},
+		attributesGroups:   []entity.AttributesGroup{},
+		attributes:         []entity.Attribute{},
+		usedExtIDs:         make(map[string]bool),
+		attributeMap:       make(map[string]bool),
+		attributesGroupMap: make(map[string]*entity.AttributesGroup),
+	}
 }
 
-func (p *sheetsParser) parseSheetData(rows [][]interface{}) ([]entity.Product, []entity.AttributesGroup, []entity.Attribute) {
-	var products []entity.Product
-	var attributesGroups []entity.AttributesGroup
-	var attributes []entity.Attribute
+func (b *menuBuilder) parseSheetData(rows [][]interface{}, cols columnIndexes, category string) {
+	b.rowsSeen += len(rows)
 
 	currentProduct := ""
 	currentPrice := 0.0
 	currentPriceOld := 0.0
+	currentExtID := ""
 	currentAttributes := []string{}
-	productExtID := 1001000
 
-	attributeMap := make(map[string]bool)
-	attributesGroupMap := make(map[string]*entity.AttributesGroup)
+	flushProduct := func() {
+		extID, generated := b.assignExtID(currentExtID, category, currentProduct)
+		product := entity.Product{
+			ExtID:          extID,
+			Name:           currentProduct,
+			Category:       category,
+			Price:          currentPrice,
+			PriceOld:       currentPriceOld,
+			Status:         string(entity.ProductStatusAvailable),
+			ExtIDGenerated: generated,
+		}
+		if len(currentAttributes) > 0 {
+			product.Attributes = map[string]interface{}{
+				"options": currentAttributes,
+			}
+		}
+		b.products = append(b.products, product)
+	}
 
 	for i, row := range rows {
-		if len(row) < 2 {
+		if len(row) <= cols.name {
 			continue
 		}
 
-		if len(row) > 1 && row[1] != nil && row[1] != "" {
-			productName := strings.TrimSpace(fmt.Sprintf("%v", row[1]))
+		if nameCell := row[cols.name]; nameCell != nil && nameCell != "" {
+			productName := strings.TrimSpace(fmt.Sprintf("%v", nameCell))
 
 			if productName == "Glovo" || productName == "" {
 				continue
 			}
 
 			if currentProduct != "" {
-				product := entity.Product{
-					ExtID:    strconv.Itoa(productExtID),
-					Name:     currentProduct,
-					Price:    currentPrice,
-					PriceOld: currentPriceOld,
-					Status:   string(entity.ProductStatusAvailable),
-				}
-				if len(currentAttributes) > 0 {
-					product.Attributes = map[string]interface{}{
-						"options": currentAttributes,
-					}
-				}
-				products = append(products, product)
-				productExtID++
+				flushProduct()
 			}
 
 			currentProduct = productName
+			currentExtID = ""
 			currentAttributes = []string{}
 
-			if len(row) > 3 && row[3] != nil {
-				if price, err := parsePrice(fmt.Sprintf("%v", row[3])); err == nil {
+			if cell := cellAt(row, cols.extID); cell != nil {
+				currentExtID = strings.TrimSpace(fmt.Sprintf("%v", cell))
+			}
+
+			if cell := cellAt(row, cols.price); cell != nil {
+				if price, err := parsePrice(fmt.Sprintf("%v", cell)); err == nil {
 					currentPrice = price
 				}
 			}
-			if len(row) > 4 && row[4] != nil {
-				if price, err := parsePrice(fmt.Sprintf("%v", row[4])); err == nil {
+			if cell := cellAt(row, cols.priceOld); cell != nil {
+				if price, err := parsePrice(fmt.Sprintf("%v", cell)); err == nil {
 					currentPriceOld = price
 				}
 			}
 		}
 
-		if len(row) > 7 && row[7] != nil {
-			attrValue := strings.TrimSpace(fmt.Sprintf("%v", row[7]))
+		if cell := cellAt(row, cols.options); cell != nil {
+			attrValue := strings.TrimSpace(fmt.Sprintf("%v", cell))
 			if attrValue != "" && attrValue != currentProduct {
 				currentAttributes = append(currentAttributes, attrValue)
 
-				if !attributeMap[attrValue] {
-					attributeMap[attrValue] = true
-					attributes = append(attributes, entity.Attribute{
-						ID:   fmt.Sprintf("attr_%d", len(attributes)),
+				if !b.attributeMap[attrValue] {
+					b.attributeMap[attrValue] = true
+					b.attributes = append(b.attributes, entity.Attribute{
+						ID:   fmt.Sprintf("attr_%d", len(b.attributes)),
 						Name: attrValue,
 					})
 				}
 			}
 		}
 
-		if len(row) > 1 && row[1] != nil {
-			categoryName := strings.TrimSpace(fmt.Sprintf("%v", row[1]))
+		if nameCell := row[cols.name]; nameCell != nil {
+			categoryName := strings.TrimSpace(fmt.Sprintf("%v", nameCell))
 			if categoryName != "" && categoryName != currentProduct &&
 				(strings.Contains(categoryName, "предложения") ||
 					strings.Contains(categoryName, "позиции") ||
 					strings.Contains(categoryName, "Glovo")) {
-				if _, exists := attributesGroupMap[categoryName]; !exists {
+				if _, exists := b.attributesGroupMap[categoryName]; !exists {
 					group := &entity.AttributesGroup{
-						ID:         fmt.Sprintf("group_%d", len(attributesGroups)),
+						ID:         fmt.Sprintf("group_%d", len(b.attributesGroups)),
 						Name:       categoryName,
 						Attributes: []entity.Attribute{},
 						IsRequired: false,
 					}
-					attributesGroups = append(attributesGroups, *group)
-					attributesGroupMap[categoryName] = group
+					b.attributesGroups = append(b.attributesGroups, *group)
+					b.attributesGroupMap[categoryName] = group
 				}
 			}
 		}
 
-		if i > 0 && len(row) > 1 && row[1] == nil && currentProduct != "" {
-			if i+1 < len(rows) && len(rows[i+1]) > 1 && rows[i+1][1] == nil {
-				if currentProduct != "" {
-					product := entity.Product{
-						ExtID:    strconv.Itoa(productExtID),
-						Name:     currentProduct,
-						Price:    currentPrice,
-						PriceOld: currentPriceOld,
-						Status:   string(entity.ProductStatusAvailable),
-					}
-					if len(currentAttributes) > 0 {
-						product.Attributes = map[string]interface{}{
-							"options": currentAttributes,
-						}
-					}
-					products = append(products, product)
-					productExtID++
-					currentProduct = ""
-					currentAttributes = []string{}
-				}
+		if i > 0 && row[cols.name] == nil && currentProduct != "" {
+			if i+1 < len(rows) && len(rows[i+1]) > cols.name && rows[i+1][cols.name] == nil {
+				flushProduct()
+				currentProduct = ""
+				currentAttributes = []string{}
 			}
 		}
 	}
 
 	if currentProduct != "" {
-		product := entity.Product{
-			ExtID:    strconv.Itoa(productExtID),
-			Name:     currentProduct,
-			Price:    currentPrice,
-			PriceOld: currentPriceOld,
-			Status:   string(entity.ProductStatusAvailable),
+		flushProduct()
+	}
+}
+
+// assignExtID prefers the ID from the sheet and otherwise derives one from the
+// product key; repeated keys within a menu get a numeric suffix, repeated
+// sheet IDs are recorded as duplicates
+func (b *menuBuilder) assignExtID(sheetID, category, name string) (string, bool) {
+	if sheetID != "" {
+		if b.usedExtIDs[sheetID] {
+			b.duplicateExtIDs = append(b.duplicateExtIDs, sheetID)
 		}
-		if len(currentAttributes) > 0 {
-			product.Attributes = map[string]interface{}{
-				"options": currentAttributes,
-			}
+		b.usedExtIDs[sheetID] = true
+		return sheetID, false
+	}
+
+	base := entity.ProductExtIDFromKey(entity.ProductKey(category, name))
+	extID := base
+	for n := 2; b.usedExtIDs[extID]; n++ {
+		extID = fmt.Sprintf("%s-%d", base, n)
+	}
+	b.usedExtIDs[extID] = true
+	return extID, true
+}
+
+// columnIndexes holds zero-based positions of mapped columns, -1 when a column is not mapped
+type columnIndexes struct {
+	extID    int
+	name     int
+	price    int
+	priceOld int
+	options  int
+}
+
+// resolveColumns turns a column mapping into positions and returns the rows
+// that follow the header row, if the mapping declares one
+func resolveColumns(mapping *entity.ColumnMapping, rows [][]interface{}) (columnIndexes, [][]interface{}, error) {
+	var header []interface{}
+	dataRows := rows
+	if mapping.HeaderRow > 0 {
+		if mapping.HeaderRow > len(rows) {
+			return columnIndexes{}, nil, fmt.Errorf("header row %d is beyond the end of the sheet", mapping.HeaderRow)
 		}
-		products = append(products, product)
+		header = rows[mapping.HeaderRow-1]
+		dataRows = rows[mapping.HeaderRow:]
 	}
 
-	return products, attributesGroups, attributes
+	var cols columnIndexes
+	refs := []struct {
+		ref    string
+		target *int
+	}{
+		{mapping.ExtID, &cols.extID},
+		{mapping.ProductName, &cols.name},
+		{mapping.Price, &cols.price},
+		{mapping.PriceOld, &cols.priceOld},
+		{mapping.Options, &cols.options},
+	}
+	for _, r := range refs {
+		idx, err := resolveColumn(r.ref, header)
+		if err != nil {
+			return columnIndexes{}, nil, err
+		}
+		*r.target = idx
+	}
+
+	return cols, dataRows, nil
+}
+
+// resolveColumn matches a reference against the header row first and
+// falls back to treating it as a column letter
+func resolveColumn(ref string, header []interface{}) (int, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return -1, nil
+	}
+
+	for i, cell := range header {
+		if cell != nil && strings.EqualFold(strings.TrimSpace(fmt.Sprintf("%v", cell)), ref) {
+			return i, nil
+		}
+	}
+
+	if idx, ok := columnLetterIndex(ref); ok {
+		return idx, nil
+	}
+
+	return -1, fmt.Errorf("column %q not found in header row", ref)
+}
+
+// columnLetterIndex converts a column letter ("A", "H", "AB") into a zero-based index
+func columnLetterIndex(ref string) (int, bool) {
+	if len(ref) == 0 || len(ref) > 3 {
+		return 0, false
+	}
+
+	idx := 0
+	for _, r := range strings.ToUpper(ref) {
+		if r < 'A' || r > 'Z' {
+			return 0, false
+		}
+		idx = idx*26 + int(r-'A'+1)
+	}
+	return idx - 1, true
+}
+
+func cellAt(row []interface{}, idx int) interface{} {
+	if idx < 0 || idx >= len(row) {
+		return nil
+	}
+	return row[idx]
 }
 
 func parsePrice(priceStr string) (float64, error) {
@@ -236,4 +432,3 @@ func parsePrice(priceStr string) (float64, error) {
 	priceStr = strings.ReplaceAll(priceStr, ",", ".")
 	return strconv.ParseFloat(priceStr, 64)
 }
-
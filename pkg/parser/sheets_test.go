@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"testing"
+
+	"menu-parser/internal/domain/entity"
+)
+
+func TestColumnLetterIndex(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want int
+		ok   bool
+	}{
+		{"A", 0, true},
+		{"h", 7, true},
+		{"Z", 25, true},
+		{"AA", 26, true},
+		{"AB", 27, true},
+		{"ZZ", 701, true},
+		{"ZZZ", 18277, true},
+		{"", 0, false},
+		{"AAAA", 0, false},
+		{"A1", 0, false},
+		{"Цена", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := columnLetterIndex(tt.ref)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("columnLetterIndex(%q) = %d, %v; want %d, %v", tt.ref, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestResolveColumns(t *testing.T) {
+	rows := [][]interface{}{
+		{"Артикул", "Название", "Цена", "Опции"},
+		{"sku-1", "Пицца", "450", "Большая"},
+	}
+
+	t.Run("letters without header row", func(t *testing.T) {
+		cols, dataRows, err := resolveColumns(entity.DefaultColumnMapping(), rows)
+		if err != nil {
+			t.Fatalf("resolveColumns: %v", err)
+		}
+		want := columnIndexes{extID: -1, name: 1, price: 3, priceOld: 4, options: 7}
+		if cols != want {
+			t.Errorf("cols = %+v, want %+v", cols, want)
+		}
+		if len(dataRows) != len(rows) {
+			t.Errorf("got %d data rows, want %d", len(dataRows), len(rows))
+		}
+	})
+
+	t.Run("header names and letters past Z", func(t *testing.T) {
+		mapping := &entity.ColumnMapping{
+			HeaderRow:   1,
+			ExtID:       "артикул",
+			ProductName: " Название ",
+			Price:       "Цена",
+			PriceOld:    "AB",
+		}
+		cols, dataRows, err := resolveColumns(mapping, rows)
+		if err != nil {
+			t.Fatalf("resolveColumns: %v", err)
+		}
+		want := columnIndexes{extID: 0, name: 1, price: 2, priceOld: 27, options: -1}
+		if cols != want {
+			t.Errorf("cols = %+v, want %+v", cols, want)
+		}
+		if len(dataRows) != 1 || dataRows[0][1] != "Пицца" {
+			t.Errorf("data rows = %v, want only the row after the header", dataRows)
+		}
+	})
+
+	t.Run("unknown header name", func(t *testing.T) {
+		mapping := &entity.ColumnMapping{HeaderRow: 1, ProductName: "Блюдо"}
+		if _, _, err := resolveColumns(mapping, rows); err == nil {
+			t.Error("expected an error for a column missing from the header row")
+		}
+	})
+
+	t.Run("header row beyond the sheet", func(t *testing.T) {
+		mapping := &entity.ColumnMapping{HeaderRow: 3, ProductName: "B"}
+		if _, _, err := resolveColumns(mapping, rows); err == nil {
+			t.Error("expected an error for a header row past the last row")
+		}
+	})
+}
+
+func TestParseSheetData(t *testing.T) {
+	cols := columnIndexes{extID: 0, name: 1, price: 3, priceOld: 4, options: 7}
+	rows := [][]interface{}{
+		{"", "Пицца", "", "450", "500", "", "", "Большая"},
+		{nil, nil, nil, nil, nil, nil, nil, "Маленькая"},
+		{"sku-2", "Суп", "", "1 200,5"},
+		{"", "Glovo"},
+		{"", "Пицца", "", "300"},
+	}
+
+	b := newMenuBuilder()
+	b.parseSheetData(rows, cols, "Основное")
+
+	if b.rowsSeen != len(rows) {
+		t.Errorf("rowsSeen = %d, want %d", b.rowsSeen, len(rows))
+	}
+	if len(b.products) != 3 {
+		t.Fatalf("got %d products, want 3: %+v", len(b.products), b.products)
+	}
+
+	pizza := b.products[0]
+	if pizza.Name != "Пицца" || pizza.Category != "Основное" || pizza.Price != 450 || pizza.PriceOld != 500 {
+		t.Errorf("unexpected first product: %+v", pizza)
+	}
+	if !pizza.ExtIDGenerated || pizza.ExtID != entity.ProductExtIDFromKey(entity.ProductKey("Основное", "Пицца")) {
+		t.Errorf("first product ext_id = %q (generated %v), want the ID derived from its key", pizza.ExtID, pizza.ExtIDGenerated)
+	}
+	options, _ := pizza.Attributes["options"].([]string)
+	if len(options) != 2 || options[0] != "Большая" || options[1] != "Маленькая" {
+		t.Errorf("first product options = %v, want [Большая Маленькая]", options)
+	}
+
+	soup := b.products[1]
+	if soup.ExtID != "sku-2" || soup.ExtIDGenerated || soup.Price != 1200.5 {
+		t.Errorf("unexpected second product: %+v", soup)
+	}
+	if soup.Attributes != nil {
+		t.Errorf("second product attributes = %v, want none", soup.Attributes)
+	}
+
+	duplicate := b.products[2]
+	if duplicate.ExtID != pizza.ExtID+"-2" {
+		t.Errorf("duplicate product ext_id = %q, want %q", duplicate.ExtID, pizza.ExtID+"-2")
+	}
+
+	if len(b.attributes) != 2 {
+		t.Errorf("got %d attributes, want 2", len(b.attributes))
+	}
+}
+
+func TestParseSheetDataDuplicateSheetIDs(t *testing.T) {
+	cols := columnIndexes{extID: 0, name: 1, price: -1, priceOld: -1, options: -1}
+
+	b := newMenuBuilder()
+	b.parseSheetData([][]interface{}{
+		{"sku-1", "Суп"},
+		{"sku-2", "Салат"},
+	}, cols, "Основное")
+	b.parseSheetData([][]interface{}{
+		{"sku-1", "Морс"},
+		{"", "Чай"},
+	}, cols, "Напитки")
+
+	if len(b.duplicateExtIDs) != 1 || b.duplicateExtIDs[0] != "sku-1" {
+		t.Errorf("duplicate IDs = %v, want [sku-1]", b.duplicateExtIDs)
+	}
+}
+
+func TestQuoteSheetName(t *testing.T) {
+	tests := map[string]string{
+		"Menu":          "'Menu'",
+		"A1":            "'A1'",
+		"Основное меню": "'Основное меню'",
+		"Chef's":        "'Chef''s'",
+		"Drinks-2024!":  "'Drinks-2024!'",
+	}
+
+	for name, want := range tests {
+		if got := quoteSheetName(name); got != want {
+			t.Errorf("quoteSheetName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/service"
+	"menu-parser/pkg/config"
+
+	"github.com/streadway/amqp"
+)
+
+// recordingAcknowledger stands in for the AMQP channel and counts how often
+// each delivery tag was settled
+type recordingAcknowledger struct {
+	mu      sync.Mutex
+	settles map[uint64]int
+	nacks   map[uint64]bool
+}
+
+func newRecordingAcknowledger() *recordingAcknowledger {
+	return &recordingAcknowledger{settles: map[uint64]int{}, nacks: map[uint64]bool{}}
+}
+
+func (a *recordingAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.settles[tag]++
+	return nil
+}
+
+func (a *recordingAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.settles[tag]++
+	a.nacks[tag] = true
+	return nil
+}
+
+func (a *recordingAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func (a *recordingAcknowledger) count(tag uint64) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.settles[tag]
+}
+
+// disconnectedRabbitMQ has retry queues configured but no broker, so
+// publishing a retry fails the way it does while reconnecting
+func disconnectedRabbitMQ() *RabbitMQ {
+	return &RabbitMQ{
+		menuParsingQueue:   "menu-parsing",
+		productStatusQueue: "product-status",
+		dlqQueue:           "dlq",
+		retryDelays:        []time.Duration{time.Second},
+		ready:              make(chan struct{}),
+		closed:             make(chan struct{}),
+	}
+}
+
+func newTestDelivery(rabbitmq *RabbitMQ, channel amqp.Acknowledger, queue string, tag uint64) *deliveryAcknowledger {
+	return &deliveryAcknowledger{
+		rabbitmq: rabbitmq,
+		delivery: amqp.Delivery{
+			Acknowledger: channel,
+			DeliveryTag:  tag,
+			RoutingKey:   queue,
+			Body:         []byte(`{}`),
+		},
+	}
+}
+
+func TestDeliveryAcknowledgerConcurrentSettlement(t *testing.T) {
+	rabbitmq := disconnectedRabbitMQ()
+	channel := newRecordingAcknowledger()
+
+	const perQueue = 200
+	var deliveries []*deliveryAcknowledger
+	tag := uint64(0)
+	for _, queue := range []string{rabbitmq.menuParsingQueue, rabbitmq.productStatusQueue} {
+		for i := 0; i < perQueue; i++ {
+			tag++
+			deliveries = append(deliveries, newTestDelivery(rabbitmq, channel, queue, tag))
+		}
+	}
+
+	start := make(chan struct{})
+	successes := make([]atomic.Int32, len(deliveries))
+	var wg sync.WaitGroup
+	for i, delivery := range deliveries {
+		settlers := []func() error{
+			delivery.Ack,
+			func() error { return delivery.Nack(false) },
+			func() error { return delivery.Nack(true) },
+		}
+		for _, settle := range settlers {
+			wg.Add(1)
+			go func(i int, settle func() error) {
+				defer wg.Done()
+				<-start
+				if settle() == nil {
+					successes[i].Add(1)
+				}
+			}(i, settle)
+		}
+	}
+	close(start)
+	wg.Wait()
+
+	for i, delivery := range deliveries {
+		tag := delivery.delivery.DeliveryTag
+		if got := successes[i].Load(); got != 1 {
+			t.Errorf("delivery %d on %s: %d settle calls succeeded, want 1", tag, delivery.delivery.RoutingKey, got)
+		}
+		if got := channel.count(tag); got != 1 {
+			t.Errorf("delivery %d on %s settled %d times on the channel, want 1", tag, delivery.delivery.RoutingKey, got)
+		}
+	}
+}
+
+func TestDeliveryAcknowledgerDoubleSettle(t *testing.T) {
+	rabbitmq := disconnectedRabbitMQ()
+	channel := newRecordingAcknowledger()
+	delivery := newTestDelivery(rabbitmq, channel, rabbitmq.menuParsingQueue, 1)
+
+	if err := delivery.Ack(); err != nil {
+		t.Fatalf("first Ack: %v", err)
+	}
+	if err := delivery.Ack(); err == nil {
+		t.Error("second Ack succeeded")
+	}
+	if err := delivery.Nack(true); err == nil {
+		t.Error("Nack after Ack succeeded")
+	}
+	if err := delivery.Retry(); err == nil {
+		t.Error("Retry after Ack succeeded")
+	}
+	if got := channel.count(1); got != 1 {
+		t.Errorf("delivery settled %d times on the channel, want 1", got)
+	}
+}
+
+func TestDeliveryAcknowledgerRetryFailure(t *testing.T) {
+	rabbitmq := disconnectedRabbitMQ()
+
+	for _, queue := range []string{rabbitmq.menuParsingQueue, rabbitmq.productStatusQueue} {
+		t.Run(queue, func(t *testing.T) {
+			channel := newRecordingAcknowledger()
+			delivery := newTestDelivery(rabbitmq, channel, queue, 1)
+
+			err := delivery.Retry()
+			if err == nil {
+				t.Fatal("Retry succeeded without a broker")
+			}
+			if queue == rabbitmq.menuParsingQueue && !errors.Is(err, ErrReconnecting) {
+				t.Errorf("Retry error = %v, want %v", err, ErrReconnecting)
+			}
+			if got := channel.count(1); got != 0 {
+				t.Fatalf("failed Retry settled the delivery %d times", got)
+			}
+
+			// The delivery is still unsettled, so the caller falls back to a nack
+			if err := delivery.Nack(true); err != nil {
+				t.Fatalf("Nack after failed Retry: %v", err)
+			}
+			if err := delivery.Ack(); err == nil {
+				t.Error("Ack after Nack succeeded")
+			}
+			if got := channel.count(1); got != 1 || !channel.nacks[1] {
+				t.Errorf("delivery settled %d times (nacked %v), want one nack", got, channel.nacks[1])
+			}
+		})
+	}
+}
+
+func TestMemoryQueueConcurrentSettlement(t *testing.T) {
+	memory := NewMemoryQueue(&config.Config{
+		RabbitMQMenuParsingQueue:   "menu-parsing",
+		RabbitMQProductStatusQueue: "product-status",
+		RabbitMQDLQQueue:           "dlq",
+		RabbitMQRetryDelays:        []time.Duration{time.Hour},
+	})
+	defer memory.Close()
+
+	const perQueue = 200
+	for i := 0; i < perQueue; i++ {
+		if err := memory.PublishMenuParsingTask(fmt.Sprintf("task-%d", i)); err != nil {
+			t.Fatalf("publish task: %v", err)
+		}
+		if err := memory.PublishProductStatusEvent(&entity.ProductStatusChangeEvent{EventID: fmt.Sprintf("event-%d", i)}); err != nil {
+			t.Fatalf("publish event: %v", err)
+		}
+	}
+
+	menuMsgs, _ := memory.ConsumeMenuParsingTasks()
+	productMsgs, _ := memory.ConsumeProductStatusEvents()
+
+	var deadLettered atomic.Int32
+	settleConcurrently := func(msg service.Message) {
+		var wg sync.WaitGroup
+		var successes atomic.Int32
+		settlers := []func() error{
+			msg.Ack,
+			msg.Retry,
+			func() error {
+				err := msg.Nack(false)
+				if err == nil {
+					deadLettered.Add(1)
+				}
+				return err
+			},
+		}
+		for _, settle := range settlers {
+			wg.Add(1)
+			go func(settle func() error) {
+				defer wg.Done()
+				if settle() == nil {
+					successes.Add(1)
+				}
+			}(settle)
+		}
+		wg.Wait()
+		if got := successes.Load(); got != 1 {
+			t.Errorf("%d settle calls succeeded for one message, want 1", got)
+		}
+	}
+
+	var workers sync.WaitGroup
+	for _, msgs := range []<-chan service.Message{menuMsgs, productMsgs} {
+		for w := 0; w < 4; w++ {
+			workers.Add(1)
+			go func(msgs <-chan service.Message) {
+				defer workers.Done()
+				for i := 0; i < perQueue/4; i++ {
+					settleConcurrently(<-msgs)
+				}
+			}(msgs)
+		}
+	}
+	workers.Wait()
+
+	letters, err := memory.ListDeadLetters(2 * perQueue)
+	if err != nil {
+		t.Fatalf("list dead letters: %v", err)
+	}
+	if len(letters) != int(deadLettered.Load()) {
+		t.Errorf("dead-letter queue holds %d messages, want %d", len(letters), deadLettered.Load())
+	}
+}
@@ -2,6 +2,7 @@ package queue
 
 import (
 	"fmt"
+	"sync/atomic"
 
 	"menu-parser/internal/domain/entity"
 	"menu-parser/internal/domain/service"
@@ -31,7 +32,6 @@ type QueueConsumerAdapter struct {
 	productStatusMsgs <-chan amqp.Delivery
 	menuOutput        chan service.Message
 	productOutput     chan service.Message
-	deliveryMap       map[uint64]amqp.Delivery
 }
 
 func NewQueueConsumer(rabbitmq *RabbitMQ) (service.QueueConsumer, error) {
@@ -51,34 +51,25 @@ func NewQueueConsumer(rabbitmq *RabbitMQ) (service.QueueConsumer, error) {
 		productStatusMsgs: productMsgs,
 		menuOutput:        make(chan service.Message, 100),
 		productOutput:     make(chan service.Message, 100),
-		deliveryMap:       make(map[uint64]amqp.Delivery),
 	}
 
-	go func() {
-		for msg := range menuMsgs {
-			adapter.deliveryMap[msg.DeliveryTag] = msg
-			adapter.menuOutput <- service.Message{
-				Body:       msg.Body,
-				DeliveryTag: msg.DeliveryTag,
-			}
-		}
-		close(adapter.menuOutput)
-	}()
-
-	go func() {
-		for msg := range productMsgs {
-			adapter.deliveryMap[msg.DeliveryTag] = msg
-			adapter.productOutput <- service.Message{
-				Body:       msg.Body,
-				DeliveryTag: msg.DeliveryTag,
-			}
-		}
-		close(adapter.productOutput)
-	}()
+	go adapter.forward(menuMsgs, adapter.menuOutput)
+	go adapter.forward(productMsgs, adapter.productOutput)
 
 	return adapter, nil
 }
 
+func (q *QueueConsumerAdapter) forward(deliveries <-chan amqp.Delivery, output chan<- service.Message) {
+	for msg := range deliveries {
+		output <- service.Message{
+			Acknowledger: &deliveryAcknowledger{rabbitmq: q.rabbitmq, delivery: msg},
+			Body:         msg.Body,
+			Attempt:      deliveryAttempt(msg),
+		}
+	}
+	close(output)
+}
+
 func (q *QueueConsumerAdapter) ConsumeMenuParsingTasks() (<-chan service.Message, error) {
 	return q.menuOutput, nil
 }
@@ -87,20 +78,44 @@ func (q *QueueConsumerAdapter) ConsumeProductStatusEvents() (<-chan service.Mess
 	return q.productOutput, nil
 }
 
-func (q *QueueConsumerAdapter) AckMessage(deliveryTag uint64) error {
-	delivery, exists := q.deliveryMap[deliveryTag]
-	if !exists {
-		return fmt.Errorf("delivery tag %d not found", deliveryTag)
+// deliveryAcknowledger settles the delivery on the channel it arrived on,
+// so nothing is shared between messages
+type deliveryAcknowledger struct {
+	rabbitmq *RabbitMQ
+	delivery amqp.Delivery
+	settled  atomic.Bool
+}
+
+func (a *deliveryAcknowledger) settle() error {
+	if !a.settled.CompareAndSwap(false, true) {
+		return fmt.Errorf("delivery %d already settled", a.delivery.DeliveryTag)
+	}
+	return nil
+}
+
+func (a *deliveryAcknowledger) Ack() error {
+	if err := a.settle(); err != nil {
+		return err
 	}
-	delete(q.deliveryMap, deliveryTag)
-	return delivery.Ack(false)
+	return a.delivery.Ack(false)
 }
 
-func (q *QueueConsumerAdapter) NackMessage(deliveryTag uint64, requeue bool) error {
-	delivery, exists := q.deliveryMap[deliveryTag]
-	if !exists {
-		return fmt.Errorf("delivery tag %d not found", deliveryTag)
+func (a *deliveryAcknowledger) Nack(requeue bool) error {
+	if err := a.settle(); err != nil {
+		return err
+	}
+	return a.delivery.Nack(false, requeue)
+}
+
+func (a *deliveryAcknowledger) Retry() error {
+	if err := a.settle(); err != nil {
+		return err
+	}
+	// The delivery stays unsettled if the retry cannot be published, so the
+	// caller can still nack it
+	if err := a.rabbitmq.PublishRetry(a.delivery); err != nil {
+		a.settled.Store(false)
+		return err
 	}
-	delete(q.deliveryMap, deliveryTag)
-	return delivery.Nack(false, requeue)
+	return a.delivery.Ack(false)
 }
@@ -0,0 +1,68 @@
+package queue
+
+import (
+	"fmt"
+
+	"menu-parser/internal/domain/service"
+	"menu-parser/pkg/config"
+)
+
+const (
+	BackendRabbitMQ = "rabbitmq"
+	BackendMemory   = "memory"
+)
+
+// Backend is the queue implementation selected by QUEUE_BACKEND
+type Backend struct {
+	Publisher   service.QueuePublisher
+	DeadLetters service.DeadLetterQueue
+	// InProcess is true when messages never leave the process, so whoever
+	// publishes them has to consume them as well
+	InProcess bool
+
+	newConsumer func() (service.QueueConsumer, error)
+	healthCheck func() error
+	close       func() error
+}
+
+func NewBackend(cfg *config.Config) (*Backend, error) {
+	switch cfg.QueueBackend {
+	case BackendRabbitMQ:
+		rabbitmq, err := NewRabbitMQ(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &Backend{
+			Publisher:   NewQueuePublisher(rabbitmq),
+			DeadLetters: NewDeadLetterQueue(rabbitmq),
+			newConsumer: func() (service.QueueConsumer, error) { return NewQueueConsumer(rabbitmq) },
+			healthCheck: rabbitmq.HealthCheck,
+			close:       rabbitmq.Close,
+		}, nil
+	case BackendMemory:
+		memory := NewMemoryQueue(cfg)
+		return &Backend{
+			Publisher:   memory,
+			DeadLetters: memory,
+			InProcess:   true,
+			newConsumer: func() (service.QueueConsumer, error) { return memory, nil },
+			healthCheck: memory.HealthCheck,
+			close:       memory.Close,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q", cfg.QueueBackend)
+	}
+}
+
+// NewConsumer starts consuming the menu parsing and product status queues
+func (b *Backend) NewConsumer() (service.QueueConsumer, error) {
+	return b.newConsumer()
+}
+
+func (b *Backend) HealthCheck() error {
+	return b.healthCheck()
+}
+
+func (b *Backend) Close() error {
+	return b.close()
+}
@@ -0,0 +1,178 @@
+package queue
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/service"
+
+	"github.com/streadway/amqp"
+)
+
+type DeadLetterQueueAdapter struct {
+	rabbitmq *RabbitMQ
+}
+
+func NewDeadLetterQueue(rabbitmq *RabbitMQ) service.DeadLetterQueue {
+	return &DeadLetterQueueAdapter{rabbitmq: rabbitmq}
+}
+
+func (q *DeadLetterQueueAdapter) ListDeadLetters(limit int) ([]entity.DeadLetter, error) {
+	letters := []entity.DeadLetter{}
+	err := q.scan(limit, func(ch *amqp.Channel, delivery amqp.Delivery) error {
+		letters = append(letters, q.toDeadLetter(delivery))
+		return nil
+	})
+	return letters, err
+}
+
+func (q *DeadLetterQueueAdapter) ReplayDeadLetters(messageIDs []string, limit int) ([]entity.DeadLetter, error) {
+	selected := toSet(messageIDs)
+	replayed := []entity.DeadLetter{}
+
+	err := q.scan(limit, func(ch *amqp.Channel, delivery amqp.Delivery) error {
+		letter := q.toDeadLetter(delivery)
+		if !selected[letter.MessageID] || letter.SourceQueue == "" {
+			return nil
+		}
+
+		// Acknowledged only once the broker confirmed the replayed copy
+		err := q.rabbitmq.publish(
+			letter.SourceQueue,
+			amqp.Publishing{
+				Headers:     replayHeaders(delivery.Headers),
+				ContentType: delivery.ContentType,
+				Body:        delivery.Body,
+				MessageId:   letter.MessageID,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to replay message %s: %w", letter.MessageID, err)
+		}
+		if err := delivery.Ack(false); err != nil {
+			return fmt.Errorf("failed to remove replayed message %s: %w", letter.MessageID, err)
+		}
+
+		replayed = append(replayed, letter)
+		return nil
+	})
+	return replayed, err
+}
+
+func (q *DeadLetterQueueAdapter) PurgeDeadLetters(messageIDs []string, limit int) ([]entity.DeadLetter, error) {
+	selected := toSet(messageIDs)
+	purged := []entity.DeadLetter{}
+
+	err := q.scan(limit, func(ch *amqp.Channel, delivery amqp.Delivery) error {
+		letter := q.toDeadLetter(delivery)
+		if !selected[letter.MessageID] {
+			return nil
+		}
+		if err := delivery.Ack(false); err != nil {
+			return fmt.Errorf("failed to purge message %s: %w", letter.MessageID, err)
+		}
+
+		purged = append(purged, letter)
+		return nil
+	})
+	return purged, err
+}
+
+// scan gets up to limit messages from the DLQ on a dedicated channel and
+// passes each to fn. Messages fn does not acknowledge go back to the queue
+// when the channel is closed.
+func (q *DeadLetterQueueAdapter) scan(limit int, fn func(ch *amqp.Channel, delivery amqp.Delivery) error) error {
+	ch, err := q.rabbitmq.openChannel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	for i := 0; i < limit; i++ {
+		delivery, ok, err := ch.Get(q.rabbitmq.dlqQueue, false)
+		if err != nil {
+			return fmt.Errorf("failed to get message from DLQ: %w", err)
+		}
+		if !ok {
+			break
+		}
+		if err := fn(ch, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (q *DeadLetterQueueAdapter) toDeadLetter(delivery amqp.Delivery) entity.DeadLetter {
+	letter := entity.DeadLetter{
+		MessageID: delivery.MessageId,
+		Body:      deadLetterBody(delivery.Body),
+	}
+	if letter.MessageID == "" {
+		// Messages published before message IDs were set are identified by their body
+		sum := sha1.Sum(delivery.Body)
+		letter.MessageID = "sha1-" + hex.EncodeToString(sum[:8])
+	}
+
+	// The most recent death comes first
+	if deaths, ok := delivery.Headers["x-death"].([]interface{}); ok && len(deaths) > 0 {
+		if death, ok := deaths[0].(amqp.Table); ok {
+			letter.SourceQueue, _ = death["queue"].(string)
+			letter.Reason, _ = death["reason"].(string)
+			letter.DeathCount, _ = death["count"].(int64)
+			if diedAt, ok := death["time"].(time.Time); ok {
+				letter.DiedAt = &diedAt
+			}
+		}
+	}
+
+	if letter.SourceQueue == q.rabbitmq.menuParsingQueue {
+		letter.TaskID = parsingTaskID(delivery.Body)
+	}
+
+	return letter
+}
+
+// replayHeaders drops the death history and retry attempts from the headers
+// of a dead-lettered message, so that its replayed copy starts over
+func replayHeaders(headers amqp.Table) amqp.Table {
+	replayed := amqp.Table{}
+	for key, value := range headers {
+		if key != "x-death" && key != AttemptHeader &&
+			key != "x-first-death-queue" && key != "x-first-death-reason" && key != "x-first-death-exchange" {
+			replayed[key] = value
+		}
+	}
+	return replayed
+}
+
+// deadLetterBody returns a JSON body as is and any other body as a JSON string
+func deadLetterBody(body []byte) json.RawMessage {
+	if json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
+
+// parsingTaskID reads the task ID of a menu parsing message
+func parsingTaskID(body []byte) string {
+	var message map[string]string
+	if json.Unmarshal(body, &message) != nil {
+		return ""
+	}
+	return message["task_id"]
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
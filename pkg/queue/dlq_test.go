@@ -0,0 +1,170 @@
+package queue
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"menu-parser/internal/domain/service"
+	"menu-parser/pkg/config"
+
+	"github.com/streadway/amqp"
+)
+
+func TestToDeadLetter(t *testing.T) {
+	adapter := &DeadLetterQueueAdapter{rabbitmq: disconnectedRabbitMQ()}
+	diedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("x-death", func(t *testing.T) {
+		letter := adapter.toDeadLetter(amqp.Delivery{
+			MessageId: "msg-1",
+			Body:      []byte(`{"task_id":"task-1"}`),
+			Headers: amqp.Table{"x-death": []interface{}{
+				// The most recent death comes first
+				amqp.Table{"queue": "menu-parsing", "reason": "rejected", "count": int64(2), "time": diedAt},
+				amqp.Table{"queue": "menu-parsing.retry.1s", "reason": "expired", "count": int64(1)},
+			}},
+		})
+		if letter.MessageID != "msg-1" || letter.SourceQueue != "menu-parsing" || letter.Reason != "rejected" || letter.DeathCount != 2 {
+			t.Errorf("letter = %+v, want msg-1 rejected twice from menu-parsing", letter)
+		}
+		if letter.DiedAt == nil || !letter.DiedAt.Equal(diedAt) {
+			t.Errorf("died at = %v, want %s", letter.DiedAt, diedAt)
+		}
+		if letter.TaskID != "task-1" {
+			t.Errorf("task ID = %q, want task-1", letter.TaskID)
+		}
+	})
+
+	t.Run("product status event", func(t *testing.T) {
+		letter := adapter.toDeadLetter(amqp.Delivery{
+			MessageId: "msg-2",
+			Body:      []byte(`{"task_id":"not-a-task"}`),
+			Headers:   amqp.Table{"x-death": []interface{}{amqp.Table{"queue": "product-status", "reason": "rejected"}}},
+		})
+		if letter.SourceQueue != "product-status" || letter.TaskID != "" {
+			t.Errorf("letter = %+v, want a product-status letter without a task", letter)
+		}
+	})
+
+	t.Run("without x-death", func(t *testing.T) {
+		for _, headers := range []amqp.Table{nil, {"x-death": "rejected"}, {"x-death": []interface{}{}}, {"x-death": []interface{}{"rejected"}}} {
+			letter := adapter.toDeadLetter(amqp.Delivery{MessageId: "msg-3", Body: []byte("not json"), Headers: headers})
+			if letter.SourceQueue != "" || letter.Reason != "" || letter.DeathCount != 0 || letter.DiedAt != nil {
+				t.Errorf("headers %v: letter = %+v, want no death details", headers, letter)
+			}
+			if string(letter.Body) != `"not json"` {
+				t.Errorf("body = %s, want the text as a JSON string", letter.Body)
+			}
+		}
+	})
+
+	t.Run("without message ID", func(t *testing.T) {
+		first := adapter.toDeadLetter(amqp.Delivery{Body: []byte(`{"task_id":"task-1"}`)})
+		second := adapter.toDeadLetter(amqp.Delivery{Body: []byte(`{"task_id":"task-1"}`)})
+		if !strings.HasPrefix(first.MessageID, "sha1-") || first.MessageID != second.MessageID {
+			t.Errorf("message IDs = %q and %q, want the same body hash", first.MessageID, second.MessageID)
+		}
+	})
+}
+
+func TestReplayHeaders(t *testing.T) {
+	headers := replayHeaders(amqp.Table{
+		"x-death":                []interface{}{amqp.Table{"queue": "menu-parsing"}},
+		"x-first-death-queue":    "menu-parsing",
+		"x-first-death-reason":   "rejected",
+		"x-first-death-exchange": "",
+		AttemptHeader:            int32(3),
+		"x-request-id":           "req-1",
+	})
+	if len(headers) != 1 || headers["x-request-id"] != "req-1" {
+		t.Errorf("headers = %v, want only x-request-id", headers)
+	}
+}
+
+func newTestMemoryQueue(t *testing.T) *MemoryQueue {
+	t.Helper()
+	memory := NewMemoryQueue(&config.Config{
+		RabbitMQMenuParsingQueue:   "menu-parsing",
+		RabbitMQProductStatusQueue: "product-status",
+		RabbitMQDLQQueue:           "dlq",
+		RabbitMQRetryDelays:        []time.Duration{time.Hour},
+	})
+	t.Cleanup(func() { memory.Close() })
+	return memory
+}
+
+// deadLetterTasks publishes a parsing task per ID and rejects each of them
+// as it arrives on msgs
+func deadLetterTasks(t *testing.T, memory *MemoryQueue, msgs <-chan service.Message, taskIDs ...string) []string {
+	t.Helper()
+	for _, taskID := range taskIDs {
+		if err := memory.PublishMenuParsingTask(taskID); err != nil {
+			t.Fatalf("publish task: %v", err)
+		}
+		if err := (<-msgs).Nack(false); err != nil {
+			t.Fatalf("reject task: %v", err)
+		}
+	}
+
+	letters, err := memory.ListDeadLetters(len(taskIDs))
+	if err != nil {
+		t.Fatalf("list dead letters: %v", err)
+	}
+	messageIDs := make([]string, 0, len(letters))
+	for i, letter := range letters {
+		if letter.TaskID != taskIDs[i] || letter.SourceQueue != "menu-parsing" || letter.Reason != "rejected" || letter.DeathCount != 1 {
+			t.Fatalf("letter = %+v, want task %s rejected from menu-parsing", letter, taskIDs[i])
+		}
+		messageIDs = append(messageIDs, letter.MessageID)
+	}
+	return messageIDs
+}
+
+func TestMemoryQueueReplayDeadLetters(t *testing.T) {
+	memory := newTestMemoryQueue(t)
+	msgs, _ := memory.ConsumeMenuParsingTasks()
+	messageIDs := deadLetterTasks(t, memory, msgs, "task-1", "task-2")
+
+	replayed, err := memory.ReplayDeadLetters([]string{messageIDs[1], "unknown"}, 10)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(replayed) != 1 || replayed[0].TaskID != "task-2" {
+		t.Fatalf("replayed = %+v, want task-2", replayed)
+	}
+
+	select {
+	case msg := <-msgs:
+		if !strings.Contains(string(msg.Body), "task-2") || msg.Attempt != 0 {
+			t.Errorf("replayed message %s at attempt %d, want task-2 at attempt 0", msg.Body, msg.Attempt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the replayed message did not reach menu-parsing")
+	}
+
+	remaining, _ := memory.ListDeadLetters(10)
+	if len(remaining) != 1 || remaining[0].MessageID != messageIDs[0] {
+		t.Errorf("dead letters left = %+v, want task-1", remaining)
+	}
+}
+
+func TestMemoryQueuePurgeDeadLetters(t *testing.T) {
+	memory := newTestMemoryQueue(t)
+	msgs, _ := memory.ConsumeMenuParsingTasks()
+	messageIDs := deadLetterTasks(t, memory, msgs, "task-1", "task-2", "task-3")
+
+	// Only the first two messages are scanned
+	purged, err := memory.PurgeDeadLetters([]string{messageIDs[0], messageIDs[2]}, 2)
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if len(purged) != 1 || purged[0].MessageID != messageIDs[0] {
+		t.Errorf("purged = %+v, want task-1 only", purged)
+	}
+
+	remaining, _ := memory.ListDeadLetters(10)
+	if len(remaining) != 2 || remaining[0].MessageID != messageIDs[1] || remaining[1].MessageID != messageIDs[2] {
+		t.Errorf("dead letters left = %+v, want task-2 and task-3", remaining)
+	}
+}
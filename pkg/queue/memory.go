@@ -0,0 +1,323 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"menu-parser/internal/domain/entity"
+	"menu-parser/internal/domain/service"
+	"menu-parser/pkg/config"
+
+	"github.com/google/uuid"
+)
+
+// MemoryQueue is an in-process queue backend with the semantics of the
+// RabbitMQ one: deliveries are acked, nacked with or without requeue, retried
+// through delayed redelivery, and rejected messages go to a dead-letter queue.
+// Messages do not survive a restart and are only seen by the process that
+// published them.
+type MemoryQueue struct {
+	menuParsing   *memoryQueue
+	productStatus *memoryQueue
+	dlq           *memoryQueue
+	retryDelays   []time.Duration
+	closed        chan struct{}
+	closeOnce     sync.Once
+}
+
+type memoryQueue struct {
+	name     string
+	mu       sync.Mutex
+	messages []*memoryMessage
+	// available is signalled when messages are pushed
+	available chan struct{}
+}
+
+type memoryMessage struct {
+	id      string
+	body    []byte
+	attempt int
+	// Set when the message is dead-lettered
+	sourceQueue string
+	reason      string
+	deathCount  int64
+	diedAt      time.Time
+}
+
+func NewMemoryQueue(cfg *config.Config) *MemoryQueue {
+	return &MemoryQueue{
+		menuParsing:   newMemoryQueue(cfg.RabbitMQMenuParsingQueue),
+		productStatus: newMemoryQueue(cfg.RabbitMQProductStatusQueue),
+		dlq:           newMemoryQueue(cfg.RabbitMQDLQQueue),
+		retryDelays:   cfg.RabbitMQRetryDelays,
+		closed:        make(chan struct{}),
+	}
+}
+
+func newMemoryQueue(name string) *memoryQueue {
+	return &memoryQueue{
+		name:      name,
+		available: make(chan struct{}, 1),
+	}
+}
+
+func (q *memoryQueue) push(msg *memoryMessage, front bool) {
+	q.mu.Lock()
+	if front {
+		q.messages = append([]*memoryMessage{msg}, q.messages...)
+	} else {
+		q.messages = append(q.messages, msg)
+	}
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *memoryQueue) signal() {
+	select {
+	case q.available <- struct{}{}:
+	default:
+	}
+}
+
+// pop waits for the next message and returns nil once closed is
+func (q *memoryQueue) pop(closed <-chan struct{}) *memoryMessage {
+	for {
+		q.mu.Lock()
+		if len(q.messages) > 0 {
+			msg := q.messages[0]
+			q.messages = q.messages[1:]
+			remaining := len(q.messages)
+			q.mu.Unlock()
+			// One signal may stand for several pushes, pass it on to the next waiter
+			if remaining > 0 {
+				q.signal()
+			}
+			return msg
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.available:
+		case <-closed:
+			return nil
+		}
+	}
+}
+
+func (m *MemoryQueue) PublishMenuParsingTask(taskID string) error {
+	body, err := json.Marshal(map[string]string{"task_id": taskID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return m.publish(m.menuParsing, body)
+}
+
+func (m *MemoryQueue) PublishProductStatusEvent(event *entity.ProductStatusChangeEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	return m.publish(m.productStatus, body)
+}
+
+func (m *MemoryQueue) publish(queue *memoryQueue, body []byte) error {
+	select {
+	case <-m.closed:
+		return ErrClosed
+	default:
+	}
+
+	queue.push(&memoryMessage{id: uuid.New().String(), body: body}, false)
+	return nil
+}
+
+func (m *MemoryQueue) ConsumeMenuParsingTasks() (<-chan service.Message, error) {
+	return m.consume(m.menuParsing), nil
+}
+
+func (m *MemoryQueue) ConsumeProductStatusEvents() (<-chan service.Message, error) {
+	return m.consume(m.productStatus), nil
+}
+
+func (m *MemoryQueue) consume(queue *memoryQueue) <-chan service.Message {
+	output := make(chan service.Message)
+	go func() {
+		defer close(output)
+		for {
+			msg := queue.pop(m.closed)
+			if msg == nil {
+				return
+			}
+
+			delivery := &memoryDelivery{memory: m, queue: queue, msg: msg}
+			select {
+			case output <- service.Message{Acknowledger: delivery, Body: msg.body, Attempt: msg.attempt}:
+			case <-m.closed:
+				return
+			}
+		}
+	}()
+	return output
+}
+
+// memoryDelivery settles a message taken from a memory queue
+type memoryDelivery struct {
+	memory  *MemoryQueue
+	queue   *memoryQueue
+	msg     *memoryMessage
+	settled atomic.Bool
+}
+
+func (d *memoryDelivery) settle() error {
+	if !d.settled.CompareAndSwap(false, true) {
+		return fmt.Errorf("message %s already settled", d.msg.id)
+	}
+	return nil
+}
+
+func (d *memoryDelivery) Ack() error {
+	return d.settle()
+}
+
+func (d *memoryDelivery) Nack(requeue bool) error {
+	if err := d.settle(); err != nil {
+		return err
+	}
+
+	if requeue {
+		d.queue.push(d.msg, true)
+		return nil
+	}
+
+	dead := *d.msg
+	dead.sourceQueue = d.queue.name
+	dead.reason = "rejected"
+	dead.deathCount++
+	dead.diedAt = time.Now()
+	d.memory.dlq.push(&dead, false)
+	return nil
+}
+
+func (d *memoryDelivery) Retry() error {
+	if d.queue != d.memory.menuParsing {
+		return fmt.Errorf("no retry queues for %q", d.queue.name)
+	}
+	if len(d.memory.retryDelays) == 0 {
+		return fmt.Errorf("no retry delays configured")
+	}
+	if err := d.settle(); err != nil {
+		return err
+	}
+
+	delay := d.memory.retryDelays[len(d.memory.retryDelays)-1]
+	if d.msg.attempt < len(d.memory.retryDelays) {
+		delay = d.memory.retryDelays[d.msg.attempt]
+	}
+
+	retry := *d.msg
+	retry.attempt++
+	time.AfterFunc(delay, func() {
+		select {
+		case <-d.memory.closed:
+		default:
+			d.queue.push(&retry, false)
+		}
+	})
+	return nil
+}
+
+func (m *MemoryQueue) ListDeadLetters(limit int) ([]entity.DeadLetter, error) {
+	m.dlq.mu.Lock()
+	defer m.dlq.mu.Unlock()
+
+	letters := []entity.DeadLetter{}
+	for i, msg := range m.dlq.messages {
+		if i == limit {
+			break
+		}
+		letters = append(letters, m.toDeadLetter(msg))
+	}
+	return letters, nil
+}
+
+func (m *MemoryQueue) ReplayDeadLetters(messageIDs []string, limit int) ([]entity.DeadLetter, error) {
+	replayed := m.takeDeadLetters(messageIDs, limit)
+
+	letters := make([]entity.DeadLetter, 0, len(replayed))
+	for _, msg := range replayed {
+		letters = append(letters, m.toDeadLetter(msg))
+
+		// The replayed message starts over: no death history and no retry attempts
+		target := m.menuParsing
+		if msg.sourceQueue == m.productStatus.name {
+			target = m.productStatus
+		}
+		target.push(&memoryMessage{id: msg.id, body: msg.body}, false)
+	}
+	return letters, nil
+}
+
+func (m *MemoryQueue) PurgeDeadLetters(messageIDs []string, limit int) ([]entity.DeadLetter, error) {
+	purged := m.takeDeadLetters(messageIDs, limit)
+
+	letters := make([]entity.DeadLetter, 0, len(purged))
+	for _, msg := range purged {
+		letters = append(letters, m.toDeadLetter(msg))
+	}
+	return letters, nil
+}
+
+// takeDeadLetters removes the selected messages among the first limit ones
+func (m *MemoryQueue) takeDeadLetters(messageIDs []string, limit int) []*memoryMessage {
+	selected := toSet(messageIDs)
+
+	m.dlq.mu.Lock()
+	defer m.dlq.mu.Unlock()
+
+	var taken, kept []*memoryMessage
+	for i, msg := range m.dlq.messages {
+		if i < limit && selected[msg.id] {
+			taken = append(taken, msg)
+		} else {
+			kept = append(kept, msg)
+		}
+	}
+	m.dlq.messages = kept
+	return taken
+}
+
+func (m *MemoryQueue) toDeadLetter(msg *memoryMessage) entity.DeadLetter {
+	diedAt := msg.diedAt
+	letter := entity.DeadLetter{
+		MessageID:   msg.id,
+		SourceQueue: msg.sourceQueue,
+		Reason:      msg.reason,
+		DeathCount:  msg.deathCount,
+		DiedAt:      &diedAt,
+		Body:        deadLetterBody(msg.body),
+	}
+	if msg.sourceQueue == m.menuParsing.name {
+		letter.TaskID = parsingTaskID(msg.body)
+	}
+	return letter
+}
+
+// Close stops the consumers; unacknowledged and delayed messages are dropped
+func (m *MemoryQueue) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.closed)
+	})
+	return nil
+}
+
+func (m *MemoryQueue) HealthCheck() error {
+	select {
+	case <-m.closed:
+		return ErrClosed
+	default:
+		return nil
+	}
+}
@@ -2,37 +2,149 @@ package queue
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"sync"
 	"time"
 
+	"menu-parser/internal/domain/apperror"
 	"menu-parser/pkg/config"
 
+	"github.com/google/uuid"
 	"github.com/streadway/amqp"
 )
 
+// AttemptHeader carries how many times a message has been retried
+const AttemptHeader = "x-attempt"
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+var (
+	// ErrReconnecting is returned while the connection to the broker is being restored
+	ErrReconnecting = apperror.New(apperror.KindUnavailable, "queue_reconnecting", "RabbitMQ connection lost, reconnecting")
+	// ErrClosed is returned after Close
+	ErrClosed = errors.New("RabbitMQ connection is closed")
+
+	// Publishing errors: the message may not have reached its queue
+	ErrPublishTimeout    = apperror.New(apperror.KindUnavailable, "queue_publish_timeout", "no publisher confirm before timeout")
+	ErrPublishNacked     = apperror.New(apperror.KindUnavailable, "queue_publish_nacked", "broker rejected the message")
+	ErrPublishUnroutable = apperror.New(apperror.KindUnavailable, "queue_unroutable", "no queue bound for the message")
+)
+
+// publisher is the publishing channel of a connection, in confirm mode.
+// Publishes are serialized, so a confirmation is matched to the last publish
+// by its sequence number.
+type publisher struct {
+	mu       sync.Mutex
+	channel  *amqp.Channel
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+	seq      uint64
+}
+
+// RabbitMQ is a supervised connection: when the connection or the publishing
+// channel drops, it reconnects with backoff, redeclares the topology, and
+// consumers are registered again on the new connection
 type RabbitMQ struct {
-	conn               *amqp.Connection
-	channel            *amqp.Channel
+	uri                string
 	menuParsingQueue   string
 	productStatusQueue string
 	dlqQueue           string
+	retryDelays        []time.Duration
+
+	menuParsingPrefetch   int
+	productStatusPrefetch int
+	publishTimeout        time.Duration
+
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	publisher *publisher
+	// ready is closed while connected and replaced when the connection drops
+	ready     chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
 }
 
 func NewRabbitMQ(cfg *config.Config) (*RabbitMQ, error) {
-	conn, err := amqp.Dial(cfg.RabbitMQURI)
+	r := &RabbitMQ{
+		uri:                cfg.RabbitMQURI,
+		menuParsingQueue:   cfg.RabbitMQMenuParsingQueue,
+		productStatusQueue: cfg.RabbitMQProductStatusQueue,
+		dlqQueue:           cfg.RabbitMQDLQQueue,
+		retryDelays:        cfg.RabbitMQRetryDelays,
+
+		menuParsingPrefetch:   cfg.MenuParsingPrefetch,
+		productStatusPrefetch: cfg.ProductStatusPrefetch,
+		publishTimeout:        cfg.RabbitMQPublishTimeout,
+
+		ready:  make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+
+	if err := r.connect(); err != nil {
+		return nil, err
+	}
+
+	go r.supervise()
+
+	return r, nil
+}
+
+// connect dials the broker, declares the topology and opens the publishing channel
+func (r *RabbitMQ) connect() error {
+	conn, err := amqp.Dial(r.uri)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
 
 	ch, err := conn.Channel()
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to open channel: %w", err)
+		return fmt.Errorf("failed to open channel: %w", err)
 	}
 
-	dlqName := cfg.RabbitMQDLQQueue
-	_, err = ch.QueueDeclare(
-		dlqName,
+	if err := r.declareTopology(ch); err != nil {
+		conn.Close()
+		return err
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	// Buffered so that confirmations of publishes that timed out do not block
+	// the connection until the next publish discards them
+	pub := &publisher{
+		channel:  ch,
+		confirms: ch.NotifyPublish(make(chan amqp.Confirmation, 64)),
+		returns:  ch.NotifyReturn(make(chan amqp.Return, 64)),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	select {
+	case <-r.closed:
+		conn.Close()
+		return ErrClosed
+	default:
+	}
+
+	r.conn = conn
+	r.publisher = pub
+	close(r.ready)
+
+	return nil
+}
+
+func (r *RabbitMQ) declareTopology(ch *amqp.Channel) error {
+	_, err := ch.QueueDeclare(
+		r.dlqQueue,
 		true,
 		false,
 		false,
@@ -40,54 +152,296 @@ func NewRabbitMQ(cfg *config.Config) (*RabbitMQ, error) {
 		nil,
 	)
 	if err != nil {
-		ch.Close()
-		conn.Close()
-		return nil, fmt.Errorf("failed to declare DLQ: %w", err)
+		return fmt.Errorf("failed to declare DLQ: %w", err)
 	}
 
-	menuParsingQueue := cfg.RabbitMQMenuParsingQueue
 	_, err = ch.QueueDeclare(
-		menuParsingQueue,
+		r.menuParsingQueue,
 		true,
 		false,
 		false,
 		false,
 		amqp.Table{
 			"x-dead-letter-exchange":    "",
-			"x-dead-letter-routing-key": dlqName,
+			"x-dead-letter-routing-key": r.dlqQueue,
 		},
 	)
 	if err != nil {
-		ch.Close()
-		conn.Close()
-		return nil, fmt.Errorf("failed to declare menu-parsing queue: %w", err)
+		return fmt.Errorf("failed to declare menu-parsing queue: %w", err)
 	}
 
-	productStatusQueue := cfg.RabbitMQProductStatusQueue
 	_, err = ch.QueueDeclare(
-		productStatusQueue,
+		r.productStatusQueue,
 		true,
 		false,
 		false,
 		false,
 		amqp.Table{
 			"x-dead-letter-exchange":    "",
-			"x-dead-letter-routing-key": dlqName,
+			"x-dead-letter-routing-key": r.dlqQueue,
 		},
 	)
 	if err != nil {
-		ch.Close()
+		return fmt.Errorf("failed to declare product-status queue: %w", err)
+	}
+
+	// Retry queues hold a message for their TTL and then dead-letter it back
+	// to the menu-parsing queue, so a failed task waits without blocking the consumer
+	for _, delay := range r.retryDelays {
+		_, err = ch.QueueDeclare(
+			retryQueueName(r.menuParsingQueue, delay),
+			true,
+			false,
+			false,
+			false,
+			amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": r.menuParsingQueue,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare retry queue: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// supervise waits for the connection or the publishing channel to drop and
+// reconnects until it succeeds or the connection is closed
+func (r *RabbitMQ) supervise() {
+	for {
+		r.mu.RLock()
+		conn, ch := r.conn, r.publisher.channel
+		r.mu.RUnlock()
+
+		connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		channelClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+		var reason *amqp.Error
+		select {
+		case <-r.closed:
+			return
+		case reason = <-connClosed:
+		case reason = <-channelClosed:
+		}
+
+		select {
+		case <-r.closed:
+			return
+		default:
+		}
+
+		log.Printf("RabbitMQ connection lost: %v", reason)
+
+		r.mu.Lock()
+		r.ready = make(chan struct{})
+		r.mu.Unlock()
 		conn.Close()
-		return nil, fmt.Errorf("failed to declare product-status queue: %w", err)
+
+		delay := minReconnectDelay
+		for {
+			select {
+			case <-r.closed:
+				return
+			case <-time.After(delay):
+			}
+
+			err := r.connect()
+			if err == nil {
+				log.Println("RabbitMQ connection restored")
+				break
+			}
+
+			log.Printf("Failed to reconnect to RabbitMQ, retrying in %s: %v", delay, err)
+			delay = min(delay*2, maxReconnectDelay)
+		}
+	}
+}
+
+// currentPublisher returns the publisher of the connection, or an error while disconnected
+func (r *RabbitMQ) currentPublisher() (*publisher, error) {
+	select {
+	case <-r.closed:
+		return nil, ErrClosed
+	default:
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	select {
+	case <-r.ready:
+		return r.publisher, nil
+	default:
+		return nil, ErrReconnecting
+	}
+}
+
+// openChannel opens a new channel on the current connection
+func (r *RabbitMQ) openChannel() (*amqp.Channel, error) {
+	if _, err := r.currentPublisher(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	conn := r.conn
+	r.mu.RUnlock()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	return ch, nil
+}
+
+// waitReady blocks until the connection is up and reports false after Close
+func (r *RabbitMQ) waitReady() bool {
+	r.mu.RLock()
+	ready := r.ready
+	r.mu.RUnlock()
+
+	select {
+	case <-ready:
+		return true
+	case <-r.closed:
+		return false
+	}
+}
+
+// publish sends a persistent message to the queue and waits until the broker
+// confirms it. A message that is nacked, cannot be routed to a queue or is not
+// confirmed within the publish timeout is reported as an error.
+func (r *RabbitMQ) publish(queue string, msg amqp.Publishing) error {
+	pub, err := r.currentPublisher()
+	if err != nil {
+		return err
+	}
+
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+
+	if msg.MessageId == "" {
+		msg.MessageId = uuid.New().String()
+	}
+	msg.DeliveryMode = amqp.Persistent
+	msg.Timestamp = time.Now()
+
+	if err := pub.channel.Publish("", queue, true, false, msg); err != nil {
+		return err
+	}
+	pub.seq++
+
+	timeout := time.NewTimer(r.publishTimeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case confirm, ok := <-pub.confirms:
+			if !ok {
+				return ErrReconnecting
+			}
+			if confirm.DeliveryTag < pub.seq {
+				// Late confirmation of an earlier publish that timed out
+				continue
+			}
+			if !confirm.Ack {
+				return ErrPublishNacked
+			}
+			// The broker sends a return before the confirmation of the same message
+			if pub.returned(msg.MessageId) {
+				return fmt.Errorf("%w: %s", ErrPublishUnroutable, queue)
+			}
+			return nil
+		case <-timeout.C:
+			return ErrPublishTimeout
+		}
+	}
+}
+
+// returned drains the pending returns and reports whether one is for the message
+func (p *publisher) returned(messageID string) bool {
+	found := false
+	for {
+		select {
+		case ret, ok := <-p.returns:
+			if !ok {
+				return found
+			}
+			if ret.MessageId == messageID {
+				found = true
+			}
+		default:
+			return found
+		}
+	}
+}
+
+func retryQueueName(queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%dms", queue, delay.Milliseconds())
+}
+
+// PublishRetry republishes a delivery to the retry queue of its attempt, with
+// the attempt header incremented. Attempts beyond the configured delays use
+// the last one.
+func (r *RabbitMQ) PublishRetry(delivery amqp.Delivery) error {
+	queue, err := r.retryQueue(delivery)
+	if err != nil {
+		return err
+	}
+
+	headers := amqp.Table{}
+	for key, value := range delivery.Headers {
+		headers[key] = value
+	}
+	headers[AttemptHeader] = int32(deliveryAttempt(delivery) + 1)
+
+	err = r.publish(
+		queue,
+		amqp.Publishing{
+			Headers:     headers,
+			ContentType: delivery.ContentType,
+			Body:        delivery.Body,
+			MessageId:   delivery.MessageId,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to publish retry: %w", err)
+	}
+
+	return nil
+}
+
+// retryQueue returns the retry queue whose delay matches the attempt of the delivery
+func (r *RabbitMQ) retryQueue(delivery amqp.Delivery) (string, error) {
+	if delivery.RoutingKey != r.menuParsingQueue {
+		return "", fmt.Errorf("no retry queues for %q", delivery.RoutingKey)
+	}
+	if len(r.retryDelays) == 0 {
+		return "", fmt.Errorf("no retry delays configured")
+	}
+
+	attempt := deliveryAttempt(delivery)
+	delay := r.retryDelays[len(r.retryDelays)-1]
+	if attempt < len(r.retryDelays) {
+		delay = r.retryDelays[attempt]
 	}
+	return retryQueueName(delivery.RoutingKey, delay), nil
+}
 
-	return &RabbitMQ{
-		conn:               conn,
-		channel:            ch,
-		menuParsingQueue:   menuParsingQueue,
-		productStatusQueue: productStatusQueue,
-		dlqQueue:           dlqName,
-	}, nil
+// deliveryAttempt reads the attempt header; a first delivery has none
+func deliveryAttempt(delivery amqp.Delivery) int {
+	switch value := delivery.Headers[AttemptHeader].(type) {
+	case int32:
+		return int(value)
+	case int64:
+		return int(value)
+	case int:
+		return value
+	default:
+		return 0
+	}
 }
 
 func (r *RabbitMQ) PublishMenuParsingTask(taskID string) error {
@@ -99,16 +453,11 @@ func (r *RabbitMQ) PublishMenuParsingTask(taskID string) error {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	err = r.channel.Publish(
-		"",
+	err = r.publish(
 		r.menuParsingQueue,
-		false,
-		false,
 		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         body,
-			DeliveryMode: amqp.Persistent,
-			Timestamp:    time.Now(),
+			ContentType: "application/json",
+			Body:        body,
 		},
 	)
 	if err != nil {
@@ -124,16 +473,11 @@ func (r *RabbitMQ) PublishProductStatusEvent(event interface{}) error {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	err = r.channel.Publish(
-		"",
+	err = r.publish(
 		r.productStatusQueue,
-		false,
-		false,
 		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         body,
-			DeliveryMode: amqp.Persistent,
-			Timestamp:    time.Now(),
+			ContentType: "application/json",
+			Body:        body,
 		},
 	)
 	if err != nil {
@@ -144,43 +488,82 @@ func (r *RabbitMQ) PublishProductStatusEvent(event interface{}) error {
 }
 
 func (r *RabbitMQ) ConsumeMenuParsingTasks() (<-chan amqp.Delivery, error) {
-	err := r.channel.Qos(
-		1,
-		0,
-		false,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to set QoS: %w", err)
-	}
+	return r.consume(r.menuParsingQueue, r.menuParsingPrefetch)
+}
 
-	msgs, err := r.channel.Consume(
-		r.menuParsingQueue,
-		"",
-		false,
-		false,
-		false,
-		false,
-		nil,
-	)
+func (r *RabbitMQ) ConsumeProductStatusEvents() (<-chan amqp.Delivery, error) {
+	return r.consume(r.productStatusQueue, r.productStatusPrefetch)
+}
+
+// consume returns deliveries of the queue for the lifetime of the connection:
+// when the broker channel drops, the consumer is registered again once the
+// connection is restored. Deliveries received before the drop can no longer be
+// acknowledged and are redelivered by the broker. The returned channel is
+// closed by Close.
+func (r *RabbitMQ) consume(queue string, prefetch int) (<-chan amqp.Delivery, error) {
+	msgs, err := r.subscribe(queue, prefetch)
 	if err != nil {
-		return nil, fmt.Errorf("failed to register consumer: %w", err)
+		return nil, err
 	}
 
-	return msgs, nil
+	output := make(chan amqp.Delivery)
+	go func() {
+		defer close(output)
+		for {
+			for msg := range msgs {
+				select {
+				case output <- msg:
+				case <-r.closed:
+					return
+				}
+			}
+
+			delay := minReconnectDelay
+			for {
+				select {
+				case <-r.closed:
+					return
+				case <-time.After(delay):
+				}
+				if !r.waitReady() {
+					return
+				}
+
+				msgs, err = r.subscribe(queue, prefetch)
+				if err == nil {
+					log.Printf("Consumer of %s registered again", queue)
+					break
+				}
+
+				log.Printf("Failed to register consumer of %s, retrying in %s: %v", queue, delay, err)
+				delay = min(delay*2, maxReconnectDelay)
+			}
+		}
+	}()
+
+	return output, nil
 }
 
-func (r *RabbitMQ) ConsumeProductStatusEvents() (<-chan amqp.Delivery, error) {
-	err := r.channel.Qos(
-		1,
+// subscribe registers a consumer on a dedicated channel, so its prefetch
+// applies to it alone and a slow queue does not hold deliveries of the other one
+func (r *RabbitMQ) subscribe(queue string, prefetch int) (<-chan amqp.Delivery, error) {
+	ch, err := r.openChannel()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ch.Qos(
+		prefetch,
 		0,
 		false,
 	)
 	if err != nil {
+		ch.Close()
 		return nil, fmt.Errorf("failed to set QoS: %w", err)
 	}
 
-	msgs, err := r.channel.Consume(
-		r.productStatusQueue,
+	msgs, err := ch.Consume(
+		queue,
 		"",
 		false,
 		false,
@@ -189,25 +572,30 @@ func (r *RabbitMQ) ConsumeProductStatusEvents() (<-chan amqp.Delivery, error) {
 		nil,
 	)
 	if err != nil {
+		ch.Close()
 		return nil, fmt.Errorf("failed to register consumer: %w", err)
 	}
 
 	return msgs, nil
 }
 
+// Close stops reconnecting and closes the connection with all its channels
 func (r *RabbitMQ) Close() error {
-	if r.channel != nil {
-		r.channel.Close()
-	}
-	if r.conn != nil {
-		return r.conn.Close()
-	}
-	return nil
+	var err error
+	r.closeOnce.Do(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		close(r.closed)
+		if !r.conn.IsClosed() {
+			err = r.conn.Close()
+		}
+	})
+	return err
 }
 
+// HealthCheck returns ErrReconnecting while the connection is being restored
 func (r *RabbitMQ) HealthCheck() error {
-	if r.conn.IsClosed() {
-		return fmt.Errorf("RabbitMQ connection is closed")
-	}
-	return nil
+	_, err := r.currentPublisher()
+	return err
 }
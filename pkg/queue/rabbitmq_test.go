@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+func TestRetryQueue(t *testing.T) {
+	rabbitmq := &RabbitMQ{
+		menuParsingQueue:   "menu-parsing",
+		productStatusQueue: "product-status",
+		retryDelays:        []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+	}
+
+	tests := []struct {
+		name    string
+		queue   string
+		headers amqp.Table
+		want    string
+	}{
+		{"first delivery", "menu-parsing", nil, "menu-parsing.retry.1000ms"},
+		{"second attempt", "menu-parsing", amqp.Table{AttemptHeader: int32(1)}, "menu-parsing.retry.2000ms"},
+		{"third attempt", "menu-parsing", amqp.Table{AttemptHeader: int32(2)}, "menu-parsing.retry.4000ms"},
+		{"beyond the delays", "menu-parsing", amqp.Table{AttemptHeader: int32(7)}, "menu-parsing.retry.4000ms"},
+		{"attempt as int64", "menu-parsing", amqp.Table{AttemptHeader: int64(1)}, "menu-parsing.retry.2000ms"},
+		// The broker's death history of the retry queues is not the attempt count
+		{"x-death only", "menu-parsing", amqp.Table{"x-death": []interface{}{amqp.Table{"count": int64(2)}}}, "menu-parsing.retry.1000ms"},
+		{"malformed attempt", "menu-parsing", amqp.Table{AttemptHeader: "2"}, "menu-parsing.retry.1000ms"},
+		{"product status", "product-status", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue, err := rabbitmq.retryQueue(amqp.Delivery{RoutingKey: tt.queue, Headers: tt.headers})
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("retry queue = %q, want an error", queue)
+				}
+				return
+			}
+			if err != nil || queue != tt.want {
+				t.Errorf("retry queue = %q, %v; want %q", queue, err, tt.want)
+			}
+		})
+	}
+
+	rabbitmq.retryDelays = nil
+	if _, err := rabbitmq.retryQueue(amqp.Delivery{RoutingKey: "menu-parsing"}); err == nil {
+		t.Error("retry queue without delays: want an error")
+	}
+}
+
+func TestRabbitMQHealthCheck(t *testing.T) {
+	rabbitmq := &RabbitMQ{ready: make(chan struct{}), closed: make(chan struct{})}
+
+	if err := rabbitmq.HealthCheck(); !errors.Is(err, ErrReconnecting) {
+		t.Errorf("health while reconnecting = %v, want %v", err, ErrReconnecting)
+	}
+
+	close(rabbitmq.ready)
+	if err := rabbitmq.HealthCheck(); err != nil {
+		t.Errorf("health while connected = %v, want nil", err)
+	}
+
+	close(rabbitmq.closed)
+	if err := rabbitmq.HealthCheck(); !errors.Is(err, ErrClosed) {
+		t.Errorf("health after close = %v, want %v", err, ErrClosed)
+	}
+}
@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"menu-parser/internal/domain/apperror"
+	"menu-parser/internal/domain/service"
+)
+
+const (
+	// SignatureHeader holds "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>"
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader holds the Unix time of the attempt, so receivers can reject replays
+	TimestampHeader = "X-Webhook-Timestamp"
+	// DeliveryHeader is the same for all attempts of one notification
+	DeliveryHeader = "X-Webhook-Delivery"
+)
+
+// errForbiddenAddress is returned when a callback host resolves to an address
+// outside the public internet
+var errForbiddenAddress = errors.New("address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range, which cloud providers
+// also use for internal services
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Sender POSTs webhook payloads signed with a shared secret
+type Sender struct {
+	client *http.Client
+	secret []byte
+}
+
+// NewSender creates a sender; with an empty secret payloads are sent unsigned.
+// Callbacks to loopback, private, link-local and other non-public addresses
+// are refused unless allowPrivateNetworks is set.
+func NewSender(secret string, timeout time.Duration, allowPrivateNetworks bool) service.WebhookSender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateNetworks {
+		// Checked on the resolved address of each connection, so a host cannot
+		// pass a check and then resolve somewhere else
+		dialer.Control = denyNonPublicAddress
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Through a proxy only the proxy's address would be checked
+	transport.Proxy = nil
+
+	return &Sender{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			// A redirected POST would turn into a GET without the payload
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		secret: []byte(secret),
+	}
+}
+
+func (s *Sender) Send(ctx context.Context, url, deliveryID string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, apperror.Wrap(err, apperror.KindInvalidArgument, "invalid_callback_url", "invalid callback URL")
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	if len(s.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(s.secret, timestamp, payload))
+	}
+
+	resp, err := s.client.Do(req)
+	if errors.Is(err, errForbiddenAddress) {
+		return 0, apperror.Wrap(err, apperror.KindInvalidArgument, "callback_address_forbidden", "callback URL does not resolve to a public address")
+	}
+	if err != nil {
+		return 0, apperror.Wrap(err, apperror.KindUnavailable, "callback_unreachable", "callback URL unreachable")
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, apperror.New(apperror.KindUnavailable, "callback_rejected", fmt.Sprintf("callback URL responded with %d", resp.StatusCode))
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<payload>"
+func Sign(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// denyNonPublicAddress is a dialer control that refuses connections to
+// addresses outside the public internet
+func denyNonPublicAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	ip := addrPort.Addr().Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%w: %s", errForbiddenAddress, ip)
+	}
+	return nil
+}
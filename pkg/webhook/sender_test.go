@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"menu-parser/internal/domain/apperror"
+)
+
+const testSecret = "secret"
+
+func TestSenderSignsPayload(t *testing.T) {
+	payload := []byte(`{"task_id":"task-1","status":"completed"}`)
+
+	var verified atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature, ok := strings.CutPrefix(r.Header.Get(SignatureHeader), "sha256=")
+		want := Sign([]byte(testSecret), r.Header.Get(TimestampHeader), body)
+		switch {
+		case !ok || !hmac.Equal([]byte(signature), []byte(want)):
+			t.Errorf("signature %q does not match the body", r.Header.Get(SignatureHeader))
+		case r.Header.Get(DeliveryHeader) != "delivery-1":
+			t.Errorf("delivery header = %q, want delivery-1", r.Header.Get(DeliveryHeader))
+		case string(body) != string(payload):
+			t.Errorf("body = %s, want %s", body, payload)
+		default:
+			verified.Store(true)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sender := NewSender(testSecret, time.Second, true)
+	statusCode, err := sender.Send(context.Background(), server.URL, "delivery-1", payload)
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if statusCode != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", statusCode, http.StatusNoContent)
+	}
+	if !verified.Load() {
+		t.Error("the receiver could not verify the request")
+	}
+}
+
+func TestSenderRejectsNon2xxResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sender := NewSender(testSecret, time.Second, true)
+	statusCode, err := sender.Send(context.Background(), server.URL, "delivery-1", []byte(`{}`))
+	if statusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", statusCode, http.StatusInternalServerError)
+	}
+	if apperror.CodeOf(err) != "callback_rejected" || apperror.IsPermanent(err) {
+		t.Errorf("error = %v, want a transient callback_rejected error", err)
+	}
+}
+
+func TestSenderRefusesPrivateAddress(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	sender := NewSender(testSecret, time.Second, false)
+	_, err := sender.Send(context.Background(), server.URL, "delivery-1", []byte(`{}`))
+	if apperror.CodeOf(err) != "callback_address_forbidden" || !apperror.IsPermanent(err) {
+		t.Errorf("error = %v, want a permanent callback_address_forbidden error", err)
+	}
+	if got := requests.Load(); got != 0 {
+		t.Errorf("server received %d requests, want none", got)
+	}
+}
+
+func TestDenyNonPublicAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:4700:4700::1111]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"10.1.2.3:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"[fd00::1]:80", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"0.0.0.0:80", false},
+		{"100.100.100.200:80", false},
+		{"224.0.0.1:80", false},
+	}
+	for _, tt := range tests {
+		err := denyNonPublicAddress("tcp", tt.address, nil)
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("%s: allowed = %v, want %v (%v)", tt.address, allowed, tt.allowed, err)
+		}
+	}
+}